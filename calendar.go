@@ -0,0 +1,546 @@
+package tokei
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar excludes whole days, such as public holidays, from a schedule.
+type Calendar interface {
+	// Excludes reports whether the day containing t is excluded.
+	Excludes(t time.Time) bool
+}
+
+// maxExcludedDays limits how many excluded days with matching times a schedule will skip before giving up.
+const maxExcludedDays = 366
+
+// WithCalendar skips any times which fall on days excluded by the calendar.
+func WithCalendar(calendar Calendar) ScheduleOption {
+	return func(s *Schedule) {
+		s.calendar = calendar
+		s.shift = false
+	}
+}
+
+// WithCalendarShift moves any times which fall on days excluded by the calendar to the same
+// time on the next day which isn't excluded, like moving payroll to the next business day.
+// A time which is shifted onto another matching time only occurs once.
+func WithCalendarShift(calendar Calendar) ScheduleOption {
+	return func(s *Schedule) {
+		s.calendar = calendar
+		s.shift = true
+	}
+}
+
+// date is a day in the calendar, independent of timezone.
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) date {
+	year, month, day := t.Date()
+	return date{year: year, month: month, day: day}
+}
+
+// DateSet is a Calendar which excludes a fixed set of dates.
+// Dates are compared in the location of the time being checked, so a date excludes the same local day in any timezone.
+type DateSet struct {
+	dates map[date]struct{}
+}
+
+// NewDateSet creates a calendar which excludes the days containing each of the given times.
+func NewDateSet(days ...time.Time) *DateSet {
+	set := &DateSet{dates: make(map[date]struct{}, len(days))}
+	for _, day := range days {
+		set.Add(day)
+	}
+	return set
+}
+
+// Add excludes the day containing t.
+func (d *DateSet) Add(t time.Time) {
+	d.dates[dateOf(t)] = struct{}{}
+}
+
+// Excludes reports whether the day containing t is in the set.
+func (d *DateSet) Excludes(t time.Time) bool {
+	_, ok := d.dates[dateOf(t)]
+	return ok
+}
+
+// ICalendar is a Calendar which excludes the days of events in an iCalendar (RFC 5545) file.
+// Events may be single or multi-day, and may repeat with an RRULE. Rules can repeat daily, weekly, monthly or
+// yearly, with INTERVAL, UNTIL and COUNT, and can pick days with BYDAY for weekly rules, BYMONTHDAY for monthly
+// and yearly rules, where -1 is the last day of the month, and BYMONTH for yearly rules. Events with other rules,
+// such as "the 4th Thursday in November", are skipped rather than failing the whole file, and counted by Skipped.
+// EXDATE and RDATE are ignored.
+type ICalendar struct {
+	dates   *DateSet
+	rules   []*icalRule
+	skipped int
+}
+
+// LoadICalendar reads an iCalendar file from disk.
+func LoadICalendar(path string) (*ICalendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseICalendar(file)
+}
+
+// ParseICalendar reads an iCalendar from r.
+func ParseICalendar(r io.Reader) (*ICalendar, error) {
+	lines, err := unfoldICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &ICalendar{dates: NewDateSet()}
+	var event *icalEvent
+	for _, line := range lines {
+		name, params, value := splitICalendarLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &icalEvent{}
+		case name == "END" && value == "VEVENT":
+			if event == nil {
+				return nil, errors.New("unexpected end of event")
+			}
+			if err := cal.add(event); err != nil {
+				return nil, err
+			}
+			event = nil
+		case event == nil:
+			continue
+		case name == "DTSTART":
+			event.start, event.allDay, err = parseICalendarTime(params, value)
+		case name == "DTEND":
+			event.end, _, err = parseICalendarTime(params, value)
+		case name == "RRULE":
+			event.rule = value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if event != nil {
+		return nil, errors.New("unterminated event")
+	}
+	return cal, nil
+}
+
+// Excludes reports whether the day containing t has an event.
+func (c *ICalendar) Excludes(t time.Time) bool {
+	if c.dates.Excludes(t) {
+		return true
+	}
+	day := utcDay(t)
+	for _, rule := range c.rules {
+		if rule.covers(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skipped returns the number of events which were skipped because their recurrence rules aren't supported.
+func (c *ICalendar) Skipped() int {
+	return c.skipped
+}
+
+// icalEvent is the part of a VEVENT needed to exclude its days.
+type icalEvent struct {
+	start, end time.Time
+	allDay     bool
+	rule       string
+}
+
+// add excludes every day the event covers.
+func (c *ICalendar) add(event *icalEvent) error {
+	if event.start.IsZero() {
+		return errors.New("event is missing a start")
+	}
+	last := event.start
+	if !event.end.IsZero() {
+		last = event.end
+		// All day events end on the day after the last excluded day, and timed events which
+		// finish at midnight don't cover the following day.
+		if event.allDay || last.Equal(startOfDay(last)) {
+			last = last.AddDate(0, 0, -1)
+		}
+	}
+	first := utcDay(event.start)
+	days := daysBetween(first, utcDay(last)) + 1
+
+	if event.rule == "" {
+		for i := 0; i < days; i++ {
+			c.dates.Add(first.AddDate(0, 0, i))
+		}
+		return nil
+	}
+	rule, err := parseICalendarRule(event.rule, first, days)
+	if err != nil {
+		return err
+	}
+	switch {
+	case rule == nil:
+		c.skipped++
+	case rule.count > 0:
+		// Counted rules are expanded up front, as whether a day is one of the first N occurrences
+		// depends on all the ones before it.
+		end := first.AddDate(searchYears, 0, 0)
+		for day, n := first, 0; n < rule.count && day.Before(end); day = day.AddDate(0, 0, 1) {
+			if !rule.occursOn(day) {
+				continue
+			}
+			n++
+			for i := 0; i < days; i++ {
+				c.dates.Add(day.AddDate(0, 0, i))
+			}
+		}
+	default:
+		c.rules = append(c.rules, rule)
+	}
+	return nil
+}
+
+// icalRule is a supported recurrence rule of an event.
+type icalRule struct {
+	frequency string
+	// start is the first day of the first occurrence, and days is how many days each occurrence covers.
+	start    time.Time
+	days     int
+	interval int
+	// until is the last day an occurrence can start on, or zero if there isn't one.
+	until time.Time
+	count int
+
+	months    []int
+	monthDays []int
+	weekdays  []int
+}
+
+// parseICalendarRule parses an RRULE value for an event starting on the day start, and covering days days.
+// It returns nil if the rule isn't supported.
+func parseICalendarRule(value string, start time.Time, days int) (*icalRule, error) {
+	rule := &icalRule{start: start, days: days, interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		equals := strings.Index(part, "=")
+		if equals < 0 {
+			return nil, errors.New("invalid recurrence rule " + value)
+		}
+		name, value := strings.ToUpper(part[:equals]), part[equals+1:]
+		var err error
+		switch name {
+		case "FREQ":
+			rule.frequency = strings.ToUpper(value)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err == nil && rule.interval < 1 {
+				err = errors.New("invalid interval " + value)
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+		case "UNTIL":
+			rule.until, _, err = parseICalendarTime(nil, value)
+			rule.until = utcDay(rule.until)
+		case "BYMONTH":
+			rule.months, err = parseICalendarNumbers(value)
+			if err == nil && !inRange(rule.months, 1, 12) {
+				return nil, nil
+			}
+		case "BYMONTHDAY":
+			rule.monthDays, err = parseICalendarNumbers(value)
+			if err == nil && (!inRange(rule.monthDays, -31, 31) || containsInt(rule.monthDays, 0)) {
+				return nil, nil
+			}
+		case "BYDAY":
+			var ok bool
+			if rule.weekdays, ok = parseICalendarWeekdays(value); !ok {
+				return nil, nil
+			}
+		case "WKST":
+			// Weeks always start on Monday, which is the default.
+			if strings.ToUpper(value) != "MO" {
+				return nil, nil
+			}
+		default:
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rule.monthDays) == 0 {
+		rule.monthDays = []int{start.Day()}
+	}
+	switch rule.frequency {
+	case "DAILY":
+		if rule.months != nil || len(rule.monthDays) > 1 || rule.monthDays[0] != start.Day() || rule.weekdays != nil {
+			return nil, nil
+		}
+	case "WEEKLY":
+		if rule.months != nil || len(rule.monthDays) > 1 || rule.monthDays[0] != start.Day() {
+			return nil, nil
+		}
+		if rule.weekdays == nil {
+			rule.weekdays = []int{int(start.Weekday())}
+		}
+	case "MONTHLY":
+		if rule.months != nil || rule.weekdays != nil {
+			return nil, nil
+		}
+	case "YEARLY":
+		if rule.weekdays != nil {
+			return nil, nil
+		}
+		if rule.months == nil {
+			rule.months = []int{int(start.Month())}
+		}
+	default:
+		return nil, nil
+	}
+	return rule, nil
+}
+
+// covers reports whether an occurrence of the rule covers day.
+func (r *icalRule) covers(day time.Time) bool {
+	for i := 0; i < r.days; i++ {
+		if r.occursOn(day.AddDate(0, 0, -i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// occursOn reports whether an occurrence of the rule starts on day.
+func (r *icalRule) occursOn(day time.Time) bool {
+	if day.Before(r.start) || (!r.until.IsZero() && day.After(r.until)) {
+		return false
+	}
+	switch r.frequency {
+	case "DAILY":
+		return daysBetween(r.start, day)%r.interval == 0
+	case "WEEKLY":
+		return containsInt(r.weekdays, int(day.Weekday())) &&
+			daysBetween(startOfWeek(r.start), startOfWeek(day))/7%r.interval == 0
+	case "MONTHLY":
+		months := (day.Year()-r.start.Year())*12 + int(day.Month()-r.start.Month())
+		return r.onMonthDay(day) && months%r.interval == 0
+	default:
+		return containsInt(r.months, int(day.Month())) && r.onMonthDay(day) &&
+			(day.Year()-r.start.Year())%r.interval == 0
+	}
+}
+
+// onMonthDay reports whether day is one of the rule's days of the month. Negative days count back from the end of
+// the month, so -1 is the last day.
+func (r *icalRule) onMonthDay(day time.Time) bool {
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return containsInt(r.monthDays, day.Day()) || containsInt(r.monthDays, day.Day()-last-1)
+}
+
+// inRange reports whether every number is from min to max.
+func inRange(numbers []int, min, max int) bool {
+	for _, n := range numbers {
+		if n < min || n > max {
+			return false
+		}
+	}
+	return true
+}
+
+// parseICalendarNumbers parses a list such as "1,15".
+func parseICalendarNumbers(value string) ([]int, error) {
+	var numbers []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// icalWeekdays maps the days used by BYDAY to time.Weekday.
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// parseICalendarWeekdays parses a BYDAY list such as "SA,SU". It returns false if it has days with an ordinal,
+// such as "4TH", which aren't supported.
+func parseICalendarWeekdays(value string) ([]int, bool) {
+	var weekdays []int
+	for _, part := range strings.Split(strings.ToUpper(value), ",") {
+		weekday, ok := icalWeekdays[part]
+		if !ok {
+			return nil, false
+		}
+		weekdays = append(weekdays, int(weekday))
+	}
+	return weekdays, true
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// utcDay returns midnight UTC on the same date as t in its own location, so that days can be counted without
+// being affected by daylight saving.
+func utcDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of days from one day returned by utcDay to another.
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// startOfWeek returns the Monday on or before a day returned by utcDay.
+func startOfWeek(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// unfoldICalendar splits r into lines, joining long lines which have been folded onto several.
+func unfoldICalendar(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICalendarLine splits a content line such as "DTSTART;VALUE=DATE:20181225".
+func splitICalendarLine(line string) (name string, params []string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	return strings.ToUpper(parts[0]), parts[1:], line[colon+1:]
+}
+
+// parseICalendarTime parses a DATE or DATE-TIME value, reporting whether it was a DATE.
+func parseICalendarTime(params []string, value string) (time.Time, bool, error) {
+	location := time.UTC
+	for _, param := range params {
+		if strings.HasPrefix(strings.ToUpper(param), "TZID=") {
+			// Values containing special characters may be quoted.
+			var err error
+			location, err = time.LoadLocation(strings.Trim(param[len("TZID="):], `"`))
+			if err != nil {
+				return time.Time{}, false, err
+			}
+		}
+	}
+
+	switch {
+	case len(value) == len("20060102"):
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	default:
+		t, err := time.ParseInLocation("20060102T150405", value, location)
+		return t, false, err
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// includedNextFromTime finds the next matching time which isn't on an excluded day.
+func (s *Schedule) includedNextFromTime(t time.Time, matchSame bool) time.Time {
+	next := s.calculateNextFromTime(t, matchSame)
+	if next.IsZero() || !s.calendar.Excludes(next) {
+		return next
+	}
+	// Skip whole excluded days at a time, counting the excluded days skipped rather than matches so that frequent
+	// schedules search as far as infrequent ones, and days between matches don't count towards the limit.
+	limit := t.AddDate(searchYears, 0, 0)
+	for skipped := 0; !next.IsZero() && s.calendar.Excludes(next); skipped++ {
+		if skipped == maxExcludedDays || !next.Before(limit) {
+			return time.Time{}
+		}
+		next = s.calculateNextFromTime(startOfDay(next).AddDate(0, 0, 1), true)
+	}
+	return next
+}
+
+// shiftedNextFromTime finds the next matching time, where times on excluded days are moved to the next included day.
+func (s *Schedule) shiftedNextFromTime(t time.Time, matchSame bool) time.Time {
+	wanted := func(candidate time.Time) bool {
+		return candidate.After(t) || (matchSame && candidate.Equal(t))
+	}
+
+	// Times on the excluded days leading up to t may have been shifted to t or later,
+	// so start searching from the first of those days.
+	from := startOfDay(t)
+	for i := 0; i < maxExcludedDays && s.calendar.Excludes(from.AddDate(0, 0, -1)); i++ {
+		from = from.AddDate(0, 0, -1)
+	}
+
+	var earliest time.Time
+	limit := t.AddDate(searchYears, 0, 0)
+	for candidate := s.calculateNextFromTime(from, true); !candidate.IsZero() && candidate.Before(limit); candidate = s.calculateNextFromTime(candidate, false) {
+		// Shifting only ever moves times later, so nothing after the earliest result so far can beat it.
+		if !earliest.IsZero() && !candidate.Before(earliest) {
+			return earliest
+		}
+		if !s.calendar.Excludes(candidate) {
+			if wanted(candidate) {
+				return candidate
+			}
+			continue
+		}
+		shifted := s.shiftForward(candidate)
+		if !shifted.IsZero() && wanted(shifted) && (earliest.IsZero() || shifted.Before(earliest)) {
+			earliest = shifted
+		}
+	}
+	return earliest
+}
+
+// shiftForward moves t to the same time on the next day which the calendar doesn't exclude.
+func (s *Schedule) shiftForward(t time.Time) time.Time {
+	day := startOfDay(t)
+	for i := 0; i < maxExcludedDays; i++ {
+		day = day.AddDate(0, 0, 1)
+		if !s.calendar.Excludes(day) {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,368 @@
+package tokei
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(1970, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDateSet(t *testing.T) {
+	set := NewDateSet(day(time.January, 2))
+	set.Add(day(time.January, 5).Add(time.Hour * 10))
+
+	assert.False(t, set.Excludes(day(time.January, 1)))
+	assert.True(t, set.Excludes(day(time.January, 2).Add(time.Hour*23)))
+	assert.True(t, set.Excludes(day(time.January, 5)))
+	assert.False(t, set.Excludes(day(time.January, 2).AddDate(1, 0, 0)))
+}
+
+func TestCalendarSkip(t *testing.T) {
+	ex, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+	holidays := NewDateSet(day(time.January, 2), day(time.January, 3))
+	sched := NewScheduleUTC(ex, WithCalendar(holidays))
+
+	expected := []time.Time{
+		day(time.January, 1).Add(time.Hour * 9),
+		day(time.January, 4).Add(time.Hour * 9),
+		day(time.January, 5).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 3))
+	assert.Equal(t, day(time.January, 4).Add(time.Hour*9), sched.NextFrom(day(time.January, 2)))
+}
+
+func TestCalendarSkipEverything(t *testing.T) {
+	ex, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+	sched := NewScheduleUTC(ex, WithCalendar(excludeAll{}))
+
+	assert.True(t, sched.NextFrom(epoch).IsZero())
+	assert.Empty(t, sched.ProjectFrom(epoch, 3))
+}
+
+func TestCalendarLongClosure(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		days    int
+		skipped time.Time
+		shifted time.Time
+	}{
+		{"three weeks", 21, day(time.January, 22), day(time.January, 22)},
+		// Skipping gives up, but the closed days' times are still shifted to the day it reopens
+		{"longer than a year", 400, time.Time{}, epoch.AddDate(0, 0, 400)},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			// Closed for the given number of days from the 1st of January.
+			closure := NewDateSet()
+			for i := 0; i < test.days; i++ {
+				closure.Add(epoch.AddDate(0, 0, i))
+			}
+			assert.Equal(t, test.skipped, NewScheduleUTC(ex, WithCalendar(closure)).NextFrom(epoch))
+			assert.Equal(t, test.shifted, NewScheduleUTC(ex, WithCalendarShift(closure)).NextFrom(epoch))
+		})
+	}
+}
+
+func TestCalendarRareSchedule(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		excluded time.Time
+		expected time.Time
+	}{
+		{"friday the 13th", "0 0 13 * 5", time.Date(2001, time.July, 13, 0, 0, 0, 0, time.UTC),
+			time.Date(2002, time.September, 13, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			// Excluding one match skips to the next, however long after it is
+			ex, err := Parse(test.input)
+			require.NoError(t, err)
+			sched := NewScheduleUTC(ex, WithCalendar(NewDateSet(test.excluded)))
+			assert.Equal(t, test.expected, sched.NextFrom(test.excluded.AddDate(0, 0, -1)))
+		})
+	}
+}
+
+type excludeAll struct{}
+
+func (excludeAll) Excludes(time.Time) bool { return true }
+
+func TestCalendarShift(t *testing.T) {
+	// Payroll on the 2nd and 20th. The 2nd is a holiday, and so is the 3rd which it would be shifted to.
+	ex, err := Parse("0 9 2,20 * *")
+	require.NoError(t, err)
+	holidays := NewDateSet(day(time.January, 2), day(time.January, 3))
+	sched := NewScheduleUTC(ex, WithCalendarShift(holidays))
+
+	expected := []time.Time{
+		day(time.January, 4).Add(time.Hour * 9),
+		day(time.January, 20).Add(time.Hour * 9),
+		day(time.February, 2).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 3))
+
+	// Searching from during the holidays still finds the shifted time.
+	assert.Equal(t, day(time.January, 4).Add(time.Hour*9), sched.NextFrom(day(time.January, 3)))
+	assert.Equal(t, day(time.January, 4).Add(time.Hour*9), sched.NextFrom(day(time.January, 4).Add(time.Hour*9)))
+	assert.Equal(t, day(time.January, 20).Add(time.Hour*9), sched.NextFrom(day(time.January, 4).Add(time.Hour*10)))
+}
+
+func TestCalendarShiftOntoMatch(t *testing.T) {
+	// A daily time shifted onto the next day only occurs once.
+	ex, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+	sched := NewScheduleUTC(ex, WithCalendarShift(NewDateSet(day(time.January, 2))))
+
+	expected := []time.Time{
+		day(time.January, 1).Add(time.Hour * 9),
+		day(time.January, 3).Add(time.Hour * 9),
+		day(time.January, 4).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 3))
+}
+
+func TestCalendarShiftOrder(t *testing.T) {
+	// Times shifted from an excluded day come before the later times on the next day.
+	ex, err := Parse("0 9,17 * * *")
+	require.NoError(t, err)
+	sched := NewScheduleUTC(ex, WithCalendarShift(NewDateSet(day(time.January, 1))))
+
+	expected := []time.Time{
+		day(time.January, 2).Add(time.Hour * 9),
+		day(time.January, 2).Add(time.Hour * 17),
+		day(time.January, 3).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 3))
+}
+
+const holidayCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//tokei//test//EN
+BEGIN:VEVENT
+UID:new-year
+DTSTART;VALUE=DATE:19700101
+DTEND;VALUE=DATE:19700102
+RRULE:FREQ=YEARLY
+SUMMARY:New Year's Day
+END:VEVENT
+BEGIN:VEVENT
+UID:christmas
+DTSTART;VALUE=DATE:19701225
+DTEND;VALUE=DATE:19701227
+SUMMARY:Christmas and
+  Boxing Day
+END:VEVENT
+BEGIN:VEVENT
+UID:outage
+DTSTART;TZID=Europe/Berlin:19700301T220000
+DTEND;TZID=Europe/Berlin:19700302T000000
+END:VEVENT
+BEGIN:VEVENT
+UID:maintenance
+DTSTART:19700401T120000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:migration
+DTSTART;TZID="America/New_York":19700501T220000
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestICalendar(t *testing.T) {
+	cal, err := ParseICalendar(strings.NewReader(holidayCalendar))
+	require.NoError(t, err)
+
+	assert.True(t, cal.Excludes(day(time.January, 1)))
+	assert.True(t, cal.Excludes(day(time.January, 1).AddDate(10, 0, 0)))
+	assert.False(t, cal.Excludes(day(time.January, 2)))
+	assert.False(t, cal.Excludes(day(time.December, 24)))
+	assert.True(t, cal.Excludes(day(time.December, 25)))
+	assert.True(t, cal.Excludes(day(time.December, 26)))
+	assert.False(t, cal.Excludes(day(time.December, 27)))
+	assert.False(t, cal.Excludes(day(time.December, 25).AddDate(1, 0, 0)))
+	assert.True(t, cal.Excludes(day(time.March, 1)))
+	assert.False(t, cal.Excludes(day(time.March, 2)))
+	assert.True(t, cal.Excludes(day(time.April, 1)))
+	assert.True(t, cal.Excludes(time.Date(1970, time.May, 1, 22, 0, 0, 0, time.UTC)))
+	assert.False(t, cal.Excludes(day(time.May, 2)))
+}
+
+func TestLoadICalendar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokei")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "holidays.ics")
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Replace(holidayCalendar, "\n", "\r\n", -1)), 0600))
+
+	cal, err := LoadICalendar(path)
+	require.NoError(t, err)
+	assert.True(t, cal.Excludes(day(time.December, 25)))
+
+	_, err = LoadICalendar(filepath.Join(dir, "missing.ics"))
+	assert.Error(t, err)
+}
+
+func TestICalendarErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"unexpected end", "END:VEVENT"},
+		{"unterminated", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700101"},
+		{"missing start", "BEGIN:VEVENT\nEND:VEVENT"},
+		{"bad date", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:1970010\nEND:VEVENT"},
+		{"bad timezone", "BEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus_Mons:19700101T000000\nEND:VEVENT"},
+		{"invalid rule", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700101\nRRULE:FREQ\nEND:VEVENT"},
+		{"invalid interval", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700101\nRRULE:FREQ=DAILY;INTERVAL=0\nEND:VEVENT"},
+		{"invalid until", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700101\nRRULE:FREQ=DAILY;UNTIL=tomorrow\nEND:VEVENT"},
+		{"invalid month", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700101\nRRULE:FREQ=YEARLY;BYMONTH=one\nEND:VEVENT"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseICalendar(strings.NewReader(test.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestICalendarRules(t *testing.T) {
+	cases := []struct {
+		name     string
+		start    string
+		rule     string
+		excluded []time.Time
+		included []time.Time
+		skipped  int
+	}{
+		{
+			name:     "yearly by month",
+			start:    "19701225",
+			rule:     "FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25",
+			excluded: []time.Time{day(time.December, 25), day(time.December, 25).AddDate(5, 0, 0)},
+			included: []time.Time{day(time.December, 25).AddDate(-1, 0, 0), day(time.December, 24), day(time.November, 25)},
+		},
+		{
+			name:     "yearly interval",
+			start:    "19700101",
+			rule:     "FREQ=YEARLY;INTERVAL=2",
+			excluded: []time.Time{day(time.January, 1), day(time.January, 1).AddDate(2, 0, 0)},
+			included: []time.Time{day(time.January, 1).AddDate(1, 0, 0)},
+		},
+		{
+			name:     "yearly until",
+			start:    "19700101",
+			rule:     "FREQ=YEARLY;UNTIL=19720101T000000Z",
+			excluded: []time.Time{day(time.January, 1), day(time.January, 1).AddDate(2, 0, 0)},
+			included: []time.Time{day(time.January, 1).AddDate(3, 0, 0)},
+		},
+		{
+			// Epoch was a Thursday, so the first weekend is the 3rd and 4th.
+			name:     "weekly by day",
+			start:    "19700103",
+			rule:     "FREQ=WEEKLY;BYDAY=SA,SU;WKST=MO",
+			excluded: []time.Time{day(time.January, 3), day(time.January, 4), day(time.January, 10), day(time.June, 7)},
+			included: []time.Time{day(time.January, 1), day(time.January, 5), day(time.January, 9)},
+		},
+		{
+			name:     "fortnightly count",
+			start:    "19700105",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			excluded: []time.Time{day(time.January, 5), day(time.January, 19), day(time.February, 2)},
+			included: []time.Time{day(time.January, 12), day(time.February, 16)},
+		},
+		{
+			name:     "monthly",
+			start:    "19700115",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=1,15",
+			excluded: []time.Time{day(time.January, 15), day(time.February, 1), day(time.March, 15)},
+			included: []time.Time{day(time.January, 1), day(time.January, 16)},
+		},
+		{
+			name:     "last day of the month",
+			start:    "19700131",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=-1",
+			excluded: []time.Time{day(time.January, 31), day(time.February, 28), day(time.April, 30)},
+			included: []time.Time{day(time.January, 30), day(time.March, 30)},
+		},
+		{
+			name:     "month day out of range",
+			start:    "19700101",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=32",
+			included: []time.Time{day(time.January, 1)},
+			skipped:  1,
+		},
+		{
+			name:     "month out of range",
+			start:    "19700101",
+			rule:     "FREQ=YEARLY;BYMONTH=13",
+			included: []time.Time{day(time.January, 1)},
+			skipped:  1,
+		},
+		{
+			name:     "daily",
+			start:    "19700110",
+			rule:     "FREQ=DAILY;INTERVAL=3;UNTIL=19700116",
+			excluded: []time.Time{day(time.January, 10), day(time.January, 13), day(time.January, 16)},
+			included: []time.Time{day(time.January, 11), day(time.January, 19)},
+		},
+		{
+			name:     "unsupported",
+			start:    "19701126",
+			rule:     "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			included: []time.Time{day(time.November, 26)},
+			skipped:  1,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			input := "BEGIN:VEVENT\nDTSTART;VALUE=DATE:" + test.start + "\nRRULE:" + test.rule + "\nEND:VEVENT\n" +
+				"BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700401\nEND:VEVENT"
+			cal, err := ParseICalendar(strings.NewReader(input))
+			require.NoError(t, err)
+
+			// The other event is still added
+			assert.True(t, cal.Excludes(day(time.April, 1)))
+			for _, excluded := range test.excluded {
+				assert.True(t, cal.Excludes(excluded), "%v should be excluded", excluded)
+			}
+			for _, included := range test.included {
+				assert.False(t, cal.Excludes(included), "%v should be included", included)
+			}
+			assert.Equal(t, test.skipped, cal.Skipped())
+		})
+	}
+}
+
+func TestICalendarMultiDayRule(t *testing.T) {
+	// A long weekend every year, from the 1st to the 3rd of May.
+	input := "BEGIN:VEVENT\nDTSTART;VALUE=DATE:19700501\nDTEND;VALUE=DATE:19700504\nRRULE:FREQ=YEARLY\nEND:VEVENT"
+	cal, err := ParseICalendar(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.False(t, cal.Excludes(day(time.April, 30).AddDate(1, 0, 0)))
+	assert.True(t, cal.Excludes(day(time.May, 1).AddDate(1, 0, 0)))
+	assert.True(t, cal.Excludes(day(time.May, 3).AddDate(1, 0, 0)))
+	assert.False(t, cal.Excludes(day(time.May, 4).AddDate(1, 0, 0)))
+}
@@ -0,0 +1,173 @@
+package tokei
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it to pass. Schedules and timers use the system clock by default,
+// but can be given a FakeClock to control time in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a timer which sends the current time on its channel after d.
+	NewTimer(d time.Duration) ClockTimer
+	// After waits for d to pass and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// ClockTimer is a timer created by a Clock.
+type ClockTimer interface {
+	// C returns the channel on which the time is sent when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer has already fired or been stopped.
+	Stop() bool
+}
+
+// SystemClock is the Clock provided by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) ClockTimer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// FakeClock is a Clock which only moves when told to. Timers fire during Advance or Set once their time is reached.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed *sync.Cond
+}
+
+// NewFakeClock creates a fake clock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer which fires once the clock has advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		c:        make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.changed.Broadcast()
+	return t
+}
+
+// After is equivalent to NewTimer(d).C().
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Advance moves the clock forward by d, firing any timers which are due in order.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing any timers which are due in order.
+// Moving the clock backwards doesn't fire anything, and moves the timers back with it, as real timers measure how
+// long they have waited on a monotonic clock which isn't changed along with the wall clock.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.Before(c.now) {
+		back := c.now.Sub(t)
+		for _, timer := range c.timers {
+			timer.deadline = timer.deadline.Add(-back)
+		}
+	}
+	c.set(t)
+}
+
+func (c *FakeClock) set(t time.Time) {
+	c.now = t
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.deadline.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- t
+	}
+	c.timers = pending
+	c.changed.Broadcast()
+}
+
+// BlockUntil waits until at least n timers are waiting to fire.
+// Tests use it to make sure a goroutine is waiting on the clock before advancing it.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// remove stops a timer from firing, reporting whether it was still waiting.
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
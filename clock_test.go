@@ -0,0 +1,90 @@
+package tokei
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	assert.Equal(t, epoch, clock.Now())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, epoch.Add(time.Hour), clock.Now())
+
+	clock.Set(epoch)
+	assert.Equal(t, epoch, clock.Now())
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	late := clock.NewTimer(time.Hour)
+	early := clock.After(time.Minute)
+	stopped := clock.NewTimer(time.Minute)
+	assert.True(t, stopped.Stop())
+
+	clock.Advance(time.Second)
+	assertNotFired(t, early)
+	assertNotFired(t, late.C())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute+time.Second), <-early)
+	assertNotFired(t, late.C())
+	assertNotFired(t, stopped.C())
+	assert.False(t, stopped.Stop())
+
+	clock.Set(epoch.Add(time.Hour * 2))
+	assert.Equal(t, epoch.Add(time.Hour*2), <-late.C())
+	assert.False(t, late.Stop())
+}
+
+func TestFakeClockBackwards(t *testing.T) {
+	clock := NewFakeClock(epoch.Add(time.Hour))
+	timer := clock.After(time.Minute)
+
+	// Timers still fire once their duration has passed after the clock goes back
+	clock.Set(epoch)
+	assertNotFired(t, timer)
+	clock.Advance(time.Second * 59)
+	assertNotFired(t, timer)
+	clock.Advance(time.Second)
+	assert.Equal(t, epoch.Add(time.Minute), <-timer)
+}
+
+func TestFakeClockImmediateTimer(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	assert.Equal(t, epoch, <-clock.After(0))
+	assert.Equal(t, epoch, <-clock.After(-time.Minute))
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	fired := make(chan time.Time)
+	go func() {
+		fired <- <-clock.After(time.Minute)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute), <-fired)
+}
+
+func TestSystemClock(t *testing.T) {
+	assert.WithinDuration(t, time.Now(), SystemClock.Now(), time.Second)
+
+	timer := SystemClock.NewTimer(time.Millisecond)
+	assert.WithinDuration(t, time.Now(), <-timer.C(), time.Second)
+	assert.False(t, timer.Stop())
+
+	assert.WithinDuration(t, time.Now(), <-SystemClock.After(time.Millisecond), time.Second)
+}
+
+func assertNotFired(t *testing.T, c <-chan time.Time) {
+	select {
+	case fired := <-c:
+		t.Errorf("timer fired unexpectedly at %v", fired)
+	default:
+	}
+}
@@ -0,0 +1,137 @@
+package tokei
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryPolicy decides what a ScheduleTimer does when it fires while earlier ticks are still waiting to be received.
+type DeliveryPolicy int
+
+// Types of DeliveryPolicy
+const (
+	// DeliverBlock waits for the reader to receive the tick, so a slow reader delays the timer. This is the default.
+	DeliverBlock DeliveryPolicy = iota
+	// DeliverDrop throws the new tick away, counting it in Dropped().
+	DeliverDrop
+	// DeliverCoalesce replaces the newest waiting tick with the new one, counting it in Coalesced(),
+	// so a reader which falls behind receives the latest time when it catches up.
+	DeliverCoalesce
+)
+
+// WithDelivery sets what the timer does when the reader isn't keeping up.
+func WithDelivery(policy DeliveryPolicy) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.delivery = policy
+	}
+}
+
+// WithBuffer lets up to n ticks wait for the reader before the delivery policy applies.
+// The timer carries on firing while ticks are waiting, and they are received in order.
+func WithBuffer(n int) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.buffer = n
+	}
+}
+
+// Dropped returns the number of ticks thrown away by DeliverDrop.
+func (st *ScheduleTimer) Dropped() uint64 {
+	return atomic.LoadUint64(&st.dropped)
+}
+
+// Coalesced returns the number of ticks replaced by a later one with DeliverCoalesce.
+func (st *ScheduleTimer) Coalesced() uint64 {
+	return atomic.LoadUint64(&st.coalesced)
+}
+
+// capacity returns how many ticks can wait for the reader.
+func (st *ScheduleTimer) capacity() int {
+	if st.delivery == DeliverCoalesce && st.buffer < 1 {
+		// Coalescing needs somewhere to keep the latest tick.
+		return 1
+	}
+	return st.buffer
+}
+
+// deliver queues the tick for the reader and applies the delivery policy if the queue is full.
+// It returns false if the timer is stopped while blocked.
+func (st *ScheduleTimer) deliver(tick Tick) bool {
+	st.pending = append(st.pending, tick)
+	st.trySend()
+	if len(st.pending) <= st.capacity() {
+		return true
+	}
+
+	switch st.delivery {
+	case DeliverDrop:
+		st.pending = st.pending[:len(st.pending)-1]
+		atomic.AddUint64(&st.dropped, 1)
+		st.emit(skipEvent, tick.Scheduled, SkipDropped)
+	case DeliverCoalesce:
+		last := len(st.pending) - 1
+		st.emit(skipEvent, st.pending[last-1].Scheduled, SkipCoalesced)
+		st.pending[last-1] = st.pending[last]
+		st.pending = st.pending[:last]
+		atomic.AddUint64(&st.coalesced, 1)
+	default:
+		for len(st.pending) > st.capacity() {
+			if !st.send() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// flush waits for the reader to receive every waiting tick, returning false if the timer is stopped first.
+func (st *ScheduleTimer) flush() bool {
+	for len(st.pending) > 0 {
+		if !st.send() {
+			return false
+		}
+	}
+	return true
+}
+
+// trySend sends waiting ticks for as long as the reader is ready for them.
+func (st *ScheduleTimer) trySend() {
+	for len(st.pending) > 0 {
+		select {
+		case st.timeChan <- st.pending[0].Scheduled:
+		case st.tickChan <- st.pending[0]:
+		default:
+			return
+		}
+		st.sent()
+	}
+}
+
+// send waits for the reader to receive the oldest waiting tick, returning false if the timer is stopped first.
+func (st *ScheduleTimer) send() bool {
+	select {
+	case st.timeChan <- st.pending[0].Scheduled:
+	case st.tickChan <- st.pending[0]:
+	case <-st.closeChan:
+		return false
+	}
+	st.sent()
+	return true
+}
+
+// sent removes the oldest waiting tick once the reader has received it.
+func (st *ScheduleTimer) sent() {
+	tick := st.pending[0]
+	st.pending = st.pending[1:]
+	if st.fireHandler != nil {
+		st.fireHandler(tick)
+	}
+}
+
+// outbox returns the channels to send the oldest waiting tick on, which are nil if nothing is waiting
+// so that a select never chooses them.
+func (st *ScheduleTimer) outbox() (chan<- time.Time, chan<- Tick, Tick) {
+	if len(st.pending) == 0 {
+		return nil, nil, Tick{}
+	}
+	return st.timeChan, st.tickChan, st.pending[0]
+}
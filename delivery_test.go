@@ -0,0 +1,102 @@
+package tokei
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowReader starts a timer which fires every minute, and lets it fire n times without anything reading from it.
+func slowReader(t *testing.T, n int, opts ...TimerOption) (*ScheduleTimer, *FakeClock) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(opts...)
+	go timer.Start()
+
+	for i := 0; i < n; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	clock.BlockUntil(1)
+	return timer, clock
+}
+
+func TestDeliverBuffered(t *testing.T) {
+	timer, _ := slowReader(t, 3, WithBuffer(3))
+	defer timer.Stop()
+
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+	assert.Equal(t, epoch.Add(time.Minute*2), <-timer.Next())
+	assert.Equal(t, epoch.Add(time.Minute*3), <-timer.Next())
+	assert.Zero(t, timer.Dropped())
+	assert.Zero(t, timer.Coalesced())
+}
+
+func TestDeliverDrop(t *testing.T) {
+	timer, clock := slowReader(t, 3, WithDelivery(DeliverDrop), WithBuffer(1))
+	defer timer.Stop()
+
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+	assert.Equal(t, uint64(2), timer.Dropped())
+
+	// A reader which keeps up receives every tick
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute*4), <-timer.Next())
+	assert.Equal(t, uint64(2), timer.Dropped())
+}
+
+func TestDeliverCoalesce(t *testing.T) {
+	timer, _ := slowReader(t, 3, WithDelivery(DeliverCoalesce))
+	defer timer.Stop()
+
+	tick := <-timer.Ticks()
+	assert.Equal(t, Tick{Scheduled: epoch.Add(time.Minute * 3), Fired: epoch.Add(time.Minute*3 + time.Second*10)}, tick)
+	assert.Equal(t, uint64(2), timer.Coalesced())
+	assert.Zero(t, timer.Dropped())
+}
+
+func TestDeliverCoalesceBuffered(t *testing.T) {
+	timer, _ := slowReader(t, 4, WithDelivery(DeliverCoalesce), WithBuffer(2))
+	defer timer.Stop()
+
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+	assert.Equal(t, epoch.Add(time.Minute*4), <-timer.Next())
+	assert.Equal(t, uint64(2), timer.Coalesced())
+}
+
+func TestDeliverFlushWhenExhausted(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock), NotAfter(epoch.Add(time.Minute*2))).Timer(WithBuffer(2))
+	go timer.Start()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	// Waiting ticks are still received before the channel is closed
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+	assert.Equal(t, epoch.Add(time.Minute*2), <-timer.Next())
+	_, ok := <-timer.Next()
+	assert.False(t, ok)
+}
+
+func TestDeliverFireHandler(t *testing.T) {
+	received := make(chan time.Time, 2)
+	timer, _ := slowReader(t, 2, WithBuffer(2), WithFireHandler(func(tick Tick) {
+		received <- tick.Scheduled
+	}))
+	defer timer.Stop()
+
+	// Only ticks which have been received are passed to the handler
+	assert.Empty(t, received)
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+	assert.Equal(t, epoch.Add(time.Minute), <-received)
+	assert.Equal(t, epoch.Add(time.Minute*2), <-timer.Next())
+	assert.Equal(t, epoch.Add(time.Minute*2), <-received)
+}
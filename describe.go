@@ -0,0 +1,133 @@
+package tokei
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describe returns an English description of when the expression matches, for example
+// "Every 5 minutes from minute 10 past hours 3 through 5 on day 1 and 2 of the month and on Tuesday in July".
+func (c *CronExpression) Describe() string {
+	return c.DescribeIn(English)
+}
+
+// DescribeIn describes when the expression matches using the given locale.
+func (c *CronExpression) DescribeIn(locale Locale) string {
+	d := describer{locale: locale}
+	minutes := canonicalize(minuteContext, c.minutes.Enumerate())
+	hours := canonicalize(hourContext, c.hours.Enumerate())
+	dayOfMonth := canonicalize(dayOfMonthContext, c.dayOfMonth.Enumerate())
+	month := canonicalize(monthContext, c.month.Enumerate())
+	dayOfWeek := canonicalize(dayOfWeekContext, c.dayOfWeek.Enumerate())
+
+	parts := []string{d.field(minutes, minutePhrases)}
+	if hours.kind != allField {
+		parts = append(parts, d.field(hours, hourPhrases))
+	}
+	if dayOfMonth.kind != allField {
+		parts = append(parts, d.field(dayOfMonth, dayOfMonthPhrases))
+	}
+	if dayOfWeek.kind != allField {
+		phrase := PhraseDayOfWeek
+		if dayOfMonth.kind != allField {
+			phrase = PhraseAndDayOfWeek
+		}
+		parts = append(parts, d.phrase(phrase, "{values}", d.names(dayOfWeek, d.weekday)))
+	}
+	if month.kind != allField {
+		parts = append(parts, d.phrase(PhraseMonth, "{values}", d.names(month, d.month)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// fieldPhrases are the phrases used to describe each kind of numeric field.
+type fieldPhrases struct {
+	all, single, list, span, repeat, repeatFrom Phrase
+}
+
+var (
+	minutePhrases = fieldPhrases{
+		all:        PhraseEveryMinute,
+		single:     PhraseAtMinute,
+		list:       PhraseAtMinutes,
+		span:       PhraseMinuteRange,
+		repeat:     PhraseMinuteRepeat,
+		repeatFrom: PhraseMinuteRepeatFrom,
+	}
+	hourPhrases = fieldPhrases{
+		single:     PhraseHour,
+		list:       PhraseHours,
+		span:       PhraseHourRange,
+		repeat:     PhraseHourRepeat,
+		repeatFrom: PhraseHourRepeatFrom,
+	}
+	dayOfMonthPhrases = fieldPhrases{
+		single:     PhraseDayOfMonth,
+		list:       PhraseDayOfMonth,
+		span:       PhraseDayOfMonthRange,
+		repeat:     PhraseDayOfMonthRepeat,
+		repeatFrom: PhraseDayOfMonthRepeatFrom,
+	}
+)
+
+// describer builds descriptions from a locale.
+type describer struct {
+	locale Locale
+}
+
+// phrase fills in the template for a phrase with pairs of placeholders and values.
+func (d describer) phrase(phrase Phrase, replacements ...string) string {
+	return strings.NewReplacer(replacements...).Replace(d.locale.Phrase(phrase))
+}
+
+// field describes a numeric field such as minutes or hours.
+func (d describer) field(f canonicalField, phrases fieldPhrases) string {
+	switch f.kind {
+	case allField:
+		return d.phrase(phrases.all)
+	case rangeField:
+		return d.phrase(phrases.span, "{start}", strconv.Itoa(f.start), "{end}", strconv.Itoa(f.end))
+	case repeatField:
+		step, nth := strconv.Itoa(f.step), d.locale.Ordinal(f.step)
+		if f.start == f.ex.Min() {
+			return d.phrase(phrases.repeat, "{step}", step, "{nth}", nth)
+		}
+		return d.phrase(phrases.repeatFrom, "{step}", step, "{nth}", nth, "{start}", strconv.Itoa(f.start))
+	default:
+		phrase := phrases.list
+		if len(f.values) == 1 {
+			phrase = phrases.single
+		}
+		return d.phrase(phrase, "{values}", d.list(f.values, strconv.Itoa))
+	}
+}
+
+// names describes months and days of the week, which read best as names rather than repeats.
+func (d describer) names(f canonicalField, name func(int) string) string {
+	if f.kind == rangeField {
+		return d.phrase(PhraseNameRange, "{start}", name(f.start), "{end}", name(f.end))
+	}
+	values := f.values
+	if f.kind == repeatField {
+		values = sequence{start: f.start, end: f.end, step: f.step}.Enumerate()
+	}
+	return d.list(values, name)
+}
+
+func (d describer) list(values []int, format func(int) string) string {
+	words := make([]string, len(values))
+	for i, value := range values {
+		words[i] = format(value)
+	}
+	return d.locale.List(words)
+}
+
+func (d describer) month(month int) string {
+	return d.locale.Month(time.Month(month))
+}
+
+// weekday names a day of the week, where both 0 and 7 are Sunday.
+func (d describer) weekday(day int) string {
+	return d.locale.Weekday(time.Weekday(day % 7))
+}
@@ -0,0 +1,44 @@
+package tokei
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"every minute", "* * * * *", "Every minute"},
+		{"single minute", "5 * * * *", "At minute 5"},
+		{"minute list", "1,2,5 * * * *", "At minutes 1, 2 and 5"},
+		{"minute range", "1-10 * * * *", "Every minute from 1 through 10"},
+		{"minute repeat", "*/10 * * * *", "Every 10 minutes"},
+		{"single hour", "0 9 * * *", "At minute 0 past hour 9"},
+		{"hour list", "30 9,17 * * *", "At minute 30 past hours 9 and 17"},
+		{"hour repeat", "0 */2 * * *", "At minute 0 past every 2 hours"},
+		{"hour repeat start", "0 1/6 * * *", "At minute 0 past every 6 hours from hour 1"},
+		{"day of month", "0 0 1 * *", "At minute 0 past hour 0 on day 1 of the month"},
+		{"day of month range", "0 0 1-7 * *", "At minute 0 past hour 0 on day 1 through 7 of the month"},
+		{"day of month repeat", "0 0 */2 * *", "At minute 0 past hour 0 on every 2nd day of the month"},
+		{"day of month repeat start", "0 0 2/3 * *", "At minute 0 past hour 0 on every 3rd day of the month from day 2"},
+		{"weekdays", "0 9 * * 1-5", "At minute 0 past hour 9 on Monday through Friday"},
+		{"weekday list", "0 9 * * 1,3,7", "At minute 0 past hour 9 on Monday, Wednesday and Sunday"},
+		{"weekday repeat", "0 9 * * */2", "At minute 0 past hour 9 on Monday, Wednesday, Friday and Sunday"},
+		{"months", "* * * 1-3 *", "Every minute in January through March"},
+		{"month list", "* * * 1,7 *", "Every minute in January and July"},
+		{"complex", "10/5 3-5 1,2 7 2", "Every 5 minutes from minute 10 past hours 3 through 5 on day 1 and 2 of the month and on Tuesday in July"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ex, err := Parse(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ex.Describe())
+		})
+	}
+}
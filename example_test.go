@@ -0,0 +1,68 @@
+package tokei_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Willyham/tokei"
+)
+
+// span stands in for a span from a tracing library such as OpenTelemetry.
+type span struct {
+	name       string
+	attributes map[string]interface{}
+}
+
+func (s *span) end(err error) {
+	fmt.Printf("end %s: %v\n", s.name, err)
+}
+
+type spanKey struct{}
+
+// spanTracer adapts a tracing library to tokei.Tracer. With OpenTelemetry, Start would call
+// otel.Tracer("tokei").Start(ctx, "tokei.run", trace.WithAttributes(...)) and end would call
+// span.RecordError(err) and span.End().
+type spanTracer struct{}
+
+func (spanTracer) Start(ctx context.Context, run tokei.TracedRun) (context.Context, func(err error)) {
+	s := &span{
+		name: "tokei.run " + run.JobID,
+		attributes: map[string]interface{}{
+			"tokei.job.id":    run.JobID,
+			"tokei.cron":      run.Expression,
+			"tokei.scheduled": run.Scheduled.Format(time.RFC3339),
+			"tokei.lag":       run.Lag.String(),
+			"tokei.attempt":   run.Attempt,
+		},
+	}
+	fmt.Printf("start %s: %v\n", s.name, s.attributes)
+	return context.WithValue(ctx, spanKey{}, s), s.end
+}
+
+func ExampleTracer() {
+	clock := tokei.NewFakeClock(time.Date(2021, 1, 1, 0, 0, 10, 0, time.UTC))
+	ex, _ := tokei.Parse("*/15 * * * *")
+	schedule := tokei.NewScheduleUTC(ex, tokei.WithClock(clock))
+
+	scheduler := tokei.NewScheduler(tokei.WithSchedulerClock(clock), tokei.WithTracer(spanTracer{}))
+	done := make(chan struct{})
+	scheduler.AddJob("report", schedule, func(ctx context.Context) error {
+		// Calls made with ctx are part of the run's span
+		s := ctx.Value(spanKey{}).(*span)
+		fmt.Printf("running in %s\n", s.name)
+		close(done)
+		return nil
+	})
+	go scheduler.Start()
+	defer scheduler.Stop(context.Background())
+
+	clock.BlockUntil(1)
+	clock.Set(time.Date(2021, 1, 1, 0, 15, 2, 0, time.UTC))
+	<-done
+
+	// Output:
+	// start tokei.run report: map[tokei.attempt:1 tokei.cron:*/15 * * * * tokei.job.id:report tokei.lag:2s tokei.scheduled:2021-01-01T00:15:00Z]
+	// running in tokei.run report
+	// end tokei.run report: <nil>
+}
@@ -75,12 +75,12 @@ func (m multiExpression) Parse(ex expressionContext, input string) (enumerator,
 	if trimmed == "*" {
 		return kleeneExpression(ex, input)
 	}
-	if m.rangeRegex.MatchString(trimmed) {
-		return rangeExpression(ex, trimmed)
-	}
 	if m.repeatRegex.MatchString(trimmed) {
 		return repeatExpression(ex, trimmed)
 	}
+	if m.rangeRegex.MatchString(trimmed) {
+		return rangeExpression(ex, trimmed)
+	}
 	if m.literalRegex.MatchString(trimmed) {
 		return literalExpression(ex, trimmed)
 	}
@@ -136,34 +136,55 @@ var rangeExpression = parseFunc(func(ex expressionContext, input string) (enumer
 	}, nil
 })
 
-// repeatExpression parses expressions of the form x/y, including */y.
+// repeatExpression parses expressions of the form x/y, including */y and x-z/y.
 var repeatExpression = parseFunc(func(ex expressionContext, input string) (enumerator, error) {
 	parts := strings.Split(input, "/")
 	if len(parts) != 2 {
 		return nil, errors.New("Invalid repeat expression, must be of form x/y")
 	}
-	end, err := parseEndValue(ex, parts[1])
+	step, err := parseEndValue(ex, parts[1])
 	if err != nil {
 		return nil, err
 	}
+	if step < 1 {
+		return nil, errors.New("invalid repeat value")
+	}
 
 	if parts[0] == "*" {
 		return sequence{
 			start: ex.Min(),
 			end:   ex.Max(),
-			step:  end,
+			step:  step,
 		}, nil
 	}
 
-	start, err := parseStartValue(ex, parts[0])
+	bounds := strings.Split(parts[0], "-")
+	if len(bounds) > 2 {
+		return nil, errors.New("Invalid repeat range, must be of form x-z/y")
+	}
+	start, err := parseStartValue(ex, bounds[0])
 	if err != nil {
 		return nil, err
 	}
+	if start > ex.Max() {
+		return nil, errors.New("invalid start value")
+	}
+
+	end := ex.Max()
+	if len(bounds) == 2 {
+		end, err = parseEndValue(ex, bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, errors.New("invalid range")
+		}
+	}
 
 	return sequence{
 		start: start,
-		end:   ex.Max(),
-		step:  end,
+		end:   end,
+		step:  step,
 	}, nil
 })
 
@@ -69,6 +69,8 @@ func TestRepeatExpression(t *testing.T) {
 	}{
 		{"star", "*/10", sequence{start: 0, end: 59, step: 10}},
 		{"normal", "5/10", sequence{start: 5, end: 59, step: 10}},
+		{"range", "0-59/15", sequence{start: 0, end: 59, step: 15}},
+		{"partial range", "10-30/10", sequence{start: 10, end: 30, step: 10}},
 	}
 
 	for _, test := range cases {
@@ -90,6 +92,12 @@ func TestRepeatExpressionError(t *testing.T) {
 		{"bad star", "10/*"},
 		{"bad start value", "a/10"},
 		{"bad end value", "10/a"},
+		{"zero repeat", "*/0"},
+		{"high start value", "60/10"},
+		{"too many range parts", "1-2-3/10"},
+		{"bad range", "30-10/10"},
+		{"bad range end", "10-a/10"},
+		{"high range end", "10-60/10"},
 	}
 
 	for _, test := range cases {
@@ -151,6 +159,7 @@ func TestMultiExpression(t *testing.T) {
 		{"range single", "25", irregularSequence{entries: []int{25}}},
 		{"repeat", "5/10", sequence{start: 5, end: 59, step: 10}},
 		{"repeat star", "*/10", sequence{start: 0, end: 59, step: 10}},
+		{"repeat range", "0-30/10", sequence{start: 0, end: 30, step: 10}},
 		{"literal", "1,2,3", irregularSequence{entries: []int{1, 2, 3}}},
 	}
 
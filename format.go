@@ -0,0 +1,144 @@
+package tokei
+
+import (
+	"strconv"
+	"strings"
+)
+
+// String returns the canonical form of the expression.
+// Each part is derived from the values it matches rather than how it was written,
+// so equivalent expressions produce the same string and the result can be passed back to Parse.
+func (c *CronExpression) String() string {
+	return strings.Join([]string{
+		canonicalize(minuteContext, c.minutes.Enumerate()).String(),
+		canonicalize(hourContext, c.hours.Enumerate()).String(),
+		canonicalize(dayOfMonthContext, c.dayOfMonth.Enumerate()).String(),
+		canonicalize(monthContext, c.month.Enumerate()).String(),
+		canonicalize(dayOfWeekContext, c.dayOfWeek.Enumerate()).String(),
+	}, " ")
+}
+
+// Normalize returns an equivalent expression in canonical form.
+func (c *CronExpression) Normalize() *CronExpression {
+	return &CronExpression{
+		minutes:    canonicalize(minuteContext, c.minutes.Enumerate()).enumerator(),
+		hours:      canonicalize(hourContext, c.hours.Enumerate()).enumerator(),
+		dayOfMonth: canonicalize(dayOfMonthContext, c.dayOfMonth.Enumerate()).enumerator(),
+		month:      canonicalize(monthContext, c.month.Enumerate()).enumerator(),
+		dayOfWeek:  canonicalize(dayOfWeekContext, c.dayOfWeek.Enumerate()).enumerator(),
+	}
+}
+
+// Equal reports whether two expressions match the same values in every part.
+// Since a time must match both the day of the month and the day of the week, this means they
+// match exactly the same times, so "*/15 * * * *" equals "0,15,30,45 * * * *".
+func Equal(a, b *CronExpression) bool {
+	return equalValues(a.minutes, b.minutes) &&
+		equalValues(a.hours, b.hours) &&
+		equalValues(a.dayOfMonth, b.dayOfMonth) &&
+		equalValues(a.month, b.month) &&
+		equalValues(a.dayOfWeek, b.dayOfWeek)
+}
+
+func equalValues(a, b enumerator) bool {
+	left, right := a.Enumerate(), b.Enumerate()
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldKind describes the shape of a canonical expression part.
+type fieldKind int
+
+// Kinds of canonical field
+const (
+	allField fieldKind = iota
+	rangeField
+	repeatField
+	listField
+)
+
+// canonicalField is the most compact description of the values matched by an expression part.
+type canonicalField struct {
+	kind   fieldKind
+	ex     expressionContext
+	start  int
+	end    int
+	step   int
+	values []int
+}
+
+// canonicalize picks the most compact form for a sorted list of values.
+// In order of preference this is "*", "x-y", "*/y" or "x/y", and finally "x,y[,z].."
+func canonicalize(ex expressionContext, values []int) canonicalField {
+	field := canonicalField{kind: listField, ex: ex, values: values}
+	if len(values) < 2 {
+		return field
+	}
+
+	first, last := values[0], values[len(values)-1]
+	step := values[1] - first
+	for i := 2; i < len(values); i++ {
+		if values[i]-values[i-1] != step {
+			return field
+		}
+	}
+
+	switch {
+	case step == 1 && first == ex.Min() && last == ex.Max():
+		field.kind = allField
+	case step == 1 && len(values) > 2:
+		field.kind = rangeField
+	case last+step > ex.Max() && (first == ex.Min() || len(values) > 2):
+		// Repeats always run to the end of the context, so they can only describe lists which do too.
+		// Like ranges, two values read better as a list unless they start at the minimum.
+		field.kind = repeatField
+	default:
+		return field
+	}
+	field.start = first
+	field.end = last
+	field.step = step
+	return field
+}
+
+// enumerator returns the enumerator Parse would produce for the field.
+func (f canonicalField) enumerator() enumerator {
+	switch f.kind {
+	case allField:
+		return sequence{start: f.ex.Min(), end: f.ex.Max(), step: 1}
+	case rangeField:
+		return sequence{start: f.start, end: f.end, step: 1}
+	case repeatField:
+		return sequence{start: f.start, end: f.ex.Max(), step: f.step}
+	default:
+		return newIrregularSequence(f.values)
+	}
+}
+
+// String formats the field as an expression part.
+func (f canonicalField) String() string {
+	switch f.kind {
+	case allField:
+		return "*"
+	case rangeField:
+		return strconv.Itoa(f.start) + "-" + strconv.Itoa(f.end)
+	case repeatField:
+		if f.start == f.ex.Min() {
+			return "*/" + strconv.Itoa(f.step)
+		}
+		return strconv.Itoa(f.start) + "/" + strconv.Itoa(f.step)
+	default:
+		parts := make([]string, len(f.values))
+		for i, value := range f.values {
+			parts[i] = strconv.Itoa(value)
+		}
+		return strings.Join(parts, ",")
+	}
+}
@@ -0,0 +1,137 @@
+package tokei
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"all", "* * * * *", "* * * * *"},
+		{"literal", "5 4 3 2 1", "5 4 3 2 1"},
+		{"collapse range", "0,1,2,3 * * * *", "0-3 * * * *"},
+		{"collapse star repeat", "0,15,30,45 * * * *", "*/15 * * * *"},
+		{"collapse repeat", "5,15,25,35,45,55 * * * *", "5/10 * * * *"},
+		{"collapse all", "* 0-23 1-31 1,2,3,4,5,6,7,8,9,10,11,12 1-7", "* * * * *"},
+		{"two values", "10,50 0,12 * * *", "10,50 */12 * * *"},
+		{"irregular", "1,2,5 * * * *", "1,2,5 * * * *"},
+		{"duplicates", "3,1,1,2 * * * *", "1-3 * * * *"},
+		{"short repeat", "*/45 * * * *", "*/45 * * * *"},
+		{"partial repeat", "0-59 */7 * * 2-6", "* */7 * * 2-6"},
+		{"complex", "10/5 3-5 1,2 7 2", "10/5 3-5 1,2 7 2"},
+		{"range repeat", "0-59/15 * * * *", "*/15 * * * *"},
+		{"partial range repeat", "0-30/10 * * * *", "0,10,20,30 * * * *"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ex, err := Parse(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ex.String())
+		})
+	}
+}
+
+// randomExpression generates expressions with an arbitrary non-empty set of values in each part.
+type randomExpression struct {
+	*CronExpression
+}
+
+func (randomExpression) Generate(r *rand.Rand, size int) reflect.Value {
+	part := func(ex expressionContext) enumerator {
+		if r.Intn(2) == 0 {
+			start := ex.Min() + r.Intn(ex.Max()-ex.Min()+1)
+			return sequence{start: start, end: ex.Max(), step: 1 + r.Intn(ex.Max())}
+		}
+		entries := []int{ex.Min() + r.Intn(ex.Max()-ex.Min()+1)}
+		for i := ex.Min(); i <= ex.Max(); i++ {
+			if r.Intn(3) == 0 {
+				entries = append(entries, i)
+			}
+		}
+		return newIrregularSequence(entries)
+	}
+	return reflect.ValueOf(randomExpression{&CronExpression{
+		minutes:    part(minuteContext),
+		hours:      part(hourContext),
+		dayOfMonth: part(dayOfMonthContext),
+		month:      part(monthContext),
+		dayOfWeek:  part(dayOfWeekContext),
+	}})
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	roundTrip := func(input randomExpression) bool {
+		parsed, err := Parse(input.String())
+		if err != nil {
+			t.Log(input.String(), err)
+			return false
+		}
+		return parsed.String() == input.String() &&
+			reflect.DeepEqual(parsed.minutes.Enumerate(), input.minutes.Enumerate()) &&
+			reflect.DeepEqual(parsed.hours.Enumerate(), input.hours.Enumerate()) &&
+			reflect.DeepEqual(parsed.dayOfMonth.Enumerate(), input.dayOfMonth.Enumerate()) &&
+			reflect.DeepEqual(parsed.month.Enumerate(), input.month.Enumerate()) &&
+			reflect.DeepEqual(parsed.dayOfWeek.Enumerate(), input.dayOfWeek.Enumerate())
+	}
+	assert.NoError(t, quick.Check(roundTrip, &quick.Config{MaxCount: 5000}))
+}
+
+func TestEqual(t *testing.T) {
+	equivalent := []string{"0,15,30,45 * * * *", "*/15 * * * *", "0-59/15 * * * *", "45,30,15,0 0-23 * 1-12 *"}
+	for _, a := range equivalent {
+		for _, b := range equivalent {
+			left, err := Parse(a)
+			require.NoError(t, err)
+			right, err := Parse(b)
+			require.NoError(t, err)
+			assert.True(t, Equal(left, right), "%s should equal %s", a, b)
+		}
+	}
+
+	cases := []struct {
+		name string
+		a, b string
+	}{
+		{"minutes", "*/15 * * * *", "*/10 * * * *"},
+		{"hours", "* 1 * * *", "* 2 * * *"},
+		{"day of month", "* * 1-5 * *", "* * 1-6 * *"},
+		{"month", "* * * 1 *", "* * * 1,2 *"},
+		{"day of week", "* * * * 1", "* * * * 7"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := Parse(test.a)
+			require.NoError(t, err)
+			b, err := Parse(test.b)
+			require.NoError(t, err)
+			assert.False(t, Equal(a, b))
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	ex, err := Parse("0,15,30,45 0-23 1,2,3 * 1-5/2")
+	require.NoError(t, err)
+
+	normal := ex.Normalize()
+	assert.True(t, Equal(ex, normal))
+	assert.Equal(t, sequence{start: 0, end: 59, step: 15}, normal.minutes)
+	assert.Equal(t, sequence{start: 0, end: 23, step: 1}, normal.hours)
+	assert.Equal(t, sequence{start: 1, end: 3, step: 1}, normal.dayOfMonth)
+	assert.Equal(t, irregularSequence{entries: []int{1, 3, 5}}, normal.dayOfWeek)
+
+	parsed, err := Parse(ex.String())
+	require.NoError(t, err)
+	assert.Equal(t, parsed, normal)
+}
@@ -0,0 +1,294 @@
+package tokei
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event describes something which happened to a scheduled time of a job or timer.
+type Event struct {
+	// JobID is the ID of the job, or "" for a ScheduleTimer.
+	JobID string
+	// Scheduled is the time which matched the schedule.
+	Scheduled time.Time
+	// Time is when the event happened.
+	Time time.Time
+	// Attempt is which attempt at running the job the event is for, starting from 1, for OnStart, OnSuccess and OnError.
+	Attempt int
+	// Duration is how long the attempt ran for, for OnSuccess and OnError.
+	Duration time.Duration
+	// Err is the error the attempt returned, for OnError.
+	Err error
+	// Reason is why the time was skipped, for OnSkip.
+	Reason SkipReason
+}
+
+// SkipReason is why a scheduled time was skipped.
+type SkipReason int
+
+// Types of SkipReason
+const (
+	// SkipOverlap is used when an earlier run of the job was still going, and its overlap policy didn't allow another.
+	SkipOverlap SkipReason = iota + 1
+	// SkipLocked is used when another scheduler had locked the run.
+	SkipLocked
+	// SkipCancelled is used when a run waiting for a worker was replaced or the scheduler was stopped.
+	SkipCancelled
+	// SkipRemoved is used when the job was removed while the run was waiting for a worker.
+	SkipRemoved
+	// SkipDropped is used when a timer's reader wasn't keeping up and the time was dropped.
+	SkipDropped
+	// SkipCoalesced is used when a timer's reader wasn't keeping up and the time was replaced by a later one.
+	SkipCoalesced
+)
+
+// String returns a short description of the reason.
+func (r SkipReason) String() string {
+	switch r {
+	case SkipOverlap:
+		return "overlap"
+	case SkipLocked:
+		return "locked"
+	case SkipCancelled:
+		return "cancelled"
+	case SkipRemoved:
+		return "removed"
+	case SkipDropped:
+		return "dropped"
+	case SkipCoalesced:
+		return "coalesced"
+	default:
+		return "unknown"
+	}
+}
+
+// Listener is told about everything a Scheduler or ScheduleTimer does, such as for audit logging.
+// Listeners are called synchronously, so should return quickly; wrap slow ones with NewAsyncListener.
+// A Scheduler calls OnStart, OnSuccess and OnError from the goroutine running the job, so listeners may be called
+// from several goroutines at once, but each run's events are seen in order. A ScheduleTimer doesn't run anything itself, so only calls OnScheduled, OnSkip and OnMisfire.
+type Listener interface {
+	// OnScheduled is called when a scheduled time is reached and the job or timer fires.
+	OnScheduled(e Event)
+	// OnStart is called before each attempt at running a job.
+	OnStart(e Event)
+	// OnSuccess is called when an attempt at running a job succeeds.
+	OnSuccess(e Event)
+	// OnError is called when an attempt at running a job returns an error.
+	OnError(e Event)
+	// OnSkip is called when a scheduled time which fired isn't run or delivered.
+	OnSkip(e Event)
+	// OnMisfire is called for each scheduled time which was missed, before the misfire policy decides whether it fires.
+	// A Scheduler which falls behind runs the earliest missed time and calls OnMisfire for the later ones it skips.
+	// After a long outage only the latest 1000 missed times are reported.
+	OnMisfire(e Event)
+}
+
+// NopListener is a Listener which does nothing. It can be embedded in listeners which only need some of the methods.
+type NopListener struct{}
+
+// OnScheduled does nothing.
+func (NopListener) OnScheduled(Event) {}
+
+// OnStart does nothing.
+func (NopListener) OnStart(Event) {}
+
+// OnSuccess does nothing.
+func (NopListener) OnSuccess(Event) {}
+
+// OnError does nothing.
+func (NopListener) OnError(Event) {}
+
+// OnSkip does nothing.
+func (NopListener) OnSkip(Event) {}
+
+// OnMisfire does nothing.
+func (NopListener) OnMisfire(Event) {}
+
+// WithListener adds a listener to the scheduler. Listeners are called in the order they were added.
+func WithListener(l Listener) SchedulerOption {
+	return func(s *Scheduler) {
+		s.listeners = append(s.listeners, l)
+	}
+}
+
+// WithTimerListener adds a listener to the timer. Listeners are called in the order they were added.
+func WithTimerListener(l Listener) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.listeners = append(st.listeners, l)
+	}
+}
+
+// eventKind is which Listener method an event is for.
+type eventKind int
+
+const (
+	scheduledEvent eventKind = iota
+	startEvent
+	successEvent
+	errorEvent
+	skipEvent
+	misfireEvent
+)
+
+// notify calls the method of l for the kind of event.
+func (k eventKind) notify(l Listener, e Event) {
+	switch k {
+	case scheduledEvent:
+		l.OnScheduled(e)
+	case startEvent:
+		l.OnStart(e)
+	case successEvent:
+		l.OnSuccess(e)
+	case errorEvent:
+		l.OnError(e)
+	case skipEvent:
+		l.OnSkip(e)
+	case misfireEvent:
+		l.OnMisfire(e)
+	}
+}
+
+// pendingEvent is an event waiting to be passed to listeners.
+type pendingEvent struct {
+	kind  eventKind
+	event Event
+}
+
+// emit passes an event to each listener.
+func emit(listeners []Listener, kind eventKind, e Event) {
+	for _, l := range listeners {
+		kind.notify(l, e)
+	}
+}
+
+// emit passes an event about a run to the scheduler's listeners straight away.
+func (s *Scheduler) emit(kind eventKind, r *run, e Event) {
+	if len(s.listeners) == 0 {
+		return
+	}
+	e.JobID, e.Scheduled = r.job.id, r.scheduled
+	if e.Time.IsZero() {
+		e.Time = s.clock.Now()
+	}
+	emit(s.listeners, kind, e)
+}
+
+// record queues an event to be passed to the listeners by flush. It is used while the scheduler's mutex is held,
+// so that listeners can call the scheduler without deadlocking.
+func (s *Scheduler) record(kind eventKind, j *job, scheduled time.Time, reason SkipReason) {
+	if len(s.listeners) == 0 {
+		return
+	}
+	s.events = append(s.events, pendingEvent{kind: kind, event: Event{
+		JobID:     j.id,
+		Scheduled: scheduled,
+		Time:      s.clock.Now(),
+		Reason:    reason,
+	}})
+}
+
+// flush passes the recorded events to the listeners, in the order they happened.
+func (s *Scheduler) flush() {
+	if len(s.listeners) == 0 {
+		return
+	}
+	s.emitMu.Lock()
+	defer s.emitMu.Unlock()
+	s.mu.Lock()
+	events := s.events
+	s.events = nil
+	s.mu.Unlock()
+	for _, pending := range events {
+		emit(s.listeners, pending.kind, pending.event)
+	}
+}
+
+// emit passes an event about a scheduled time to the timer's listeners.
+func (st *ScheduleTimer) emit(kind eventKind, scheduled time.Time, reason SkipReason) {
+	if len(st.listeners) == 0 {
+		return
+	}
+	emit(st.listeners, kind, Event{Scheduled: scheduled, Time: st.clock.Now(), Reason: reason})
+}
+
+// AsyncListener passes events to another listener from its own goroutine, so that slow listeners don't hold up the
+// scheduler. Events are passed on in order. If the buffer is full, events are dropped rather than holding up the
+// scheduler, and counted by Dropped.
+type AsyncListener struct {
+	// dropped is accessed atomically, so is kept first for alignment.
+	dropped  uint64
+	listener Listener
+	events   chan pendingEvent
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewAsyncListener starts passing events to l, buffering up to buffer events while it is busy.
+func NewAsyncListener(l Listener, buffer int) *AsyncListener {
+	a := &AsyncListener{
+		listener: l,
+		events:   make(chan pendingEvent, buffer),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(a.done)
+		for pending := range a.events {
+			pending.kind.notify(a.listener, pending.event)
+		}
+	}()
+	return a
+}
+
+// Dropped returns the number of events thrown away because the buffer was full.
+func (a *AsyncListener) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// queue buffers an event, or drops it if the buffer is full.
+func (a *AsyncListener) queue(kind eventKind, e Event) {
+	select {
+	case a.events <- pendingEvent{kind: kind, event: e}:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+// Close waits for buffered events to be passed on and stops the listener. It must be called once the scheduler or
+// timer using it has stopped, as it can't be sent any more events afterwards. It is safe to call more than once.
+func (a *AsyncListener) Close() {
+	a.once.Do(func() {
+		close(a.events)
+	})
+	<-a.done
+}
+
+// OnScheduled queues the event.
+func (a *AsyncListener) OnScheduled(e Event) {
+	a.queue(scheduledEvent, e)
+}
+
+// OnStart queues the event.
+func (a *AsyncListener) OnStart(e Event) {
+	a.queue(startEvent, e)
+}
+
+// OnSuccess queues the event.
+func (a *AsyncListener) OnSuccess(e Event) {
+	a.queue(successEvent, e)
+}
+
+// OnError queues the event.
+func (a *AsyncListener) OnError(e Event) {
+	a.queue(errorEvent, e)
+}
+
+// OnSkip queues the event.
+func (a *AsyncListener) OnSkip(e Event) {
+	a.queue(skipEvent, e)
+}
+
+// OnMisfire queues the event.
+func (a *AsyncListener) OnMisfire(e Event) {
+	a.queue(misfireEvent, e)
+}
@@ -0,0 +1,337 @@
+package tokei
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedEvent is an event passed to a recordingListener, with the name of the method it was passed to.
+type recordedEvent struct {
+	method string
+	event  Event
+}
+
+// recordingListener sends every event it is passed on events.
+type recordingListener struct {
+	events chan recordedEvent
+}
+
+func newRecordingListener() *recordingListener {
+	return &recordingListener{events: make(chan recordedEvent, 100)}
+}
+
+func (l *recordingListener) OnScheduled(e Event) { l.events <- recordedEvent{"OnScheduled", e} }
+func (l *recordingListener) OnStart(e Event)     { l.events <- recordedEvent{"OnStart", e} }
+func (l *recordingListener) OnSuccess(e Event)   { l.events <- recordedEvent{"OnSuccess", e} }
+func (l *recordingListener) OnError(e Event)     { l.events <- recordedEvent{"OnError", e} }
+func (l *recordingListener) OnSkip(e Event)      { l.events <- recordedEvent{"OnSkip", e} }
+func (l *recordingListener) OnMisfire(e Event)   { l.events <- recordedEvent{"OnMisfire", e} }
+
+// next returns the next n events the listener was passed.
+func (l *recordingListener) next(t *testing.T, n int) []recordedEvent {
+	var events []recordedEvent
+	for i := 0; i < n; i++ {
+		select {
+		case e := <-l.events:
+			events = append(events, e)
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for event")
+		}
+	}
+	return events
+}
+
+func TestListenerScheduler(t *testing.T) {
+	listener := newRecordingListener()
+	s, clock := startScheduler(t, WithListener(listener))
+	failed := errors.New("failed")
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(ctx context.Context) error {
+		clock.Advance(time.Second)
+		if ScheduledTime(ctx).Equal(epoch.Add(time.Minute * 2)) {
+			return failed
+		}
+		return nil
+	}))
+
+	cases := []struct {
+		name   string
+		result string
+		err    error
+	}{
+		{"success", "OnSuccess", nil},
+		{"error", "OnError", failed},
+	}
+
+	for i, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			scheduled := epoch.Add(time.Minute * time.Duration(i+1))
+			clock.BlockUntil(1)
+			clock.Set(scheduled)
+
+			events := listener.next(t, 3)
+			assert.Equal(t, recordedEvent{"OnScheduled", Event{JobID: "job", Scheduled: scheduled, Time: scheduled}}, events[0])
+			assert.Equal(t, recordedEvent{"OnStart", Event{JobID: "job", Scheduled: scheduled, Time: scheduled, Attempt: 1}}, events[1])
+			assert.Equal(t, recordedEvent{test.result, Event{
+				JobID:     "job",
+				Scheduled: scheduled,
+				Time:      scheduled.Add(time.Second),
+				Attempt:   1,
+				Duration:  time.Second,
+				Err:       test.err,
+			}}, events[2])
+			waitIdle(t, s, "job")
+		})
+	}
+}
+
+func TestListenerSkip(t *testing.T) {
+	listener := newRecordingListener()
+	s, clock := startScheduler(t, WithListener(listener))
+	job := newSlowJob()
+	defer close(job.release)
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), job.run, WithOverlapPolicy(OverlapForbid)))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-job.started
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	events := listener.next(t, 4)
+	assert.Equal(t, []string{"OnScheduled", "OnStart", "OnScheduled", "OnSkip"}, []string{
+		events[0].method, events[1].method, events[2].method, events[3].method,
+	})
+	assert.Equal(t, epoch.Add(time.Minute*2), events[3].event.Scheduled)
+	assert.Equal(t, SkipOverlap, events[3].event.Reason)
+}
+
+func TestListenerLocked(t *testing.T) {
+	listener := newRecordingListener()
+	locked := LockerFunc(func(context.Context, string, time.Time, time.Duration) (bool, error) {
+		return false, nil
+	})
+	s, clock := startScheduler(t, WithListener(listener), WithLocker(locked, time.Minute))
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(context.Context) error {
+		return nil
+	}))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	events := listener.next(t, 2)
+	assert.Equal(t, "OnScheduled", events[0].method)
+	assert.Equal(t, recordedEvent{"OnSkip", Event{
+		JobID:     "job",
+		Scheduled: epoch.Add(time.Minute),
+		Time:      epoch.Add(time.Minute + time.Second*10),
+		Reason:    SkipLocked,
+	}}, events[1])
+}
+
+func TestListenerMissed(t *testing.T) {
+	listener := newRecordingListener()
+	s, clock := startScheduler(t, WithListener(listener))
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(context.Context) error {
+		return nil
+	}))
+
+	// The scheduler wakes up at 00:03:30, runs 00:01 and skips the times in between
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute*3 + time.Second*30))
+	var misfired []time.Time
+	for _, e := range listener.next(t, 5) {
+		switch e.method {
+		case "OnScheduled":
+			assert.Equal(t, epoch.Add(time.Minute), e.event.Scheduled)
+		case "OnMisfire":
+			assert.Equal(t, clock.Now(), e.event.Time)
+			misfired = append(misfired, e.event.Scheduled)
+		}
+	}
+	assert.Equal(t, []time.Time{epoch.Add(time.Minute * 2), epoch.Add(time.Minute * 3)}, misfired)
+	waitIdle(t, s, "job")
+}
+
+func TestListenerMissedUntilNow(t *testing.T) {
+	listener := newRecordingListener()
+	s, clock := startScheduler(t, WithListener(listener))
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "*/5 * * * *", clock), func(context.Context) error {
+		return nil
+	}))
+
+	// The scheduler wakes up exactly at 00:10, so runs 00:05, skips nothing and runs 00:10 next
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute * 10))
+	var scheduled []time.Time
+	for _, e := range listener.next(t, 6) {
+		assert.NotEqual(t, "OnMisfire", e.method)
+		if e.method == "OnScheduled" {
+			scheduled = append(scheduled, e.event.Scheduled)
+		}
+	}
+	assert.Equal(t, []time.Time{epoch.Add(time.Minute * 5), epoch.Add(time.Minute * 10)}, scheduled)
+	waitIdle(t, s, "job")
+}
+
+func TestListenerRestore(t *testing.T) {
+	// The job last ran at midnight and the scheduler was restarted at 02:30.
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(JobRecord{ID: "job", Schedule: mustSchedule(t, "0 * * * *"), Misfire: MisfireSkip}))
+	require.NoError(t, store.SetRun("job", epoch, epoch.Add(time.Hour)))
+
+	listener := newRecordingListener()
+	clock := NewFakeClock(epoch.Add(time.Hour*2 + time.Minute*30))
+	s := NewScheduler(WithSchedulerClock(clock), WithStore(store), WithListener(listener))
+	require.NoError(t, s.Restore(func(string) JobFunc {
+		return func(context.Context) error {
+			return nil
+		}
+	}))
+
+	events := listener.next(t, 2)
+	for i, e := range events {
+		assert.Equal(t, recordedEvent{"OnMisfire", Event{
+			JobID:     "job",
+			Scheduled: epoch.Add(time.Hour * time.Duration(i+1)),
+			Time:      clock.Now(),
+		}}, e)
+	}
+	assert.Empty(t, listener.events)
+}
+
+func TestListenerTimer(t *testing.T) {
+	listener := newRecordingListener()
+	timer, _ := slowReader(t, 3, WithDelivery(DeliverDrop), WithBuffer(1), WithTimerListener(listener))
+	defer timer.Stop()
+
+	events := listener.next(t, 5)
+	assert.Equal(t, recordedEvent{"OnScheduled", Event{Scheduled: epoch.Add(time.Minute), Time: epoch.Add(time.Minute + time.Second*10)}}, events[0])
+	for i, e := range events[1:] {
+		scheduled := epoch.Add(time.Minute * time.Duration(i/2+2))
+		if i%2 == 0 {
+			assert.Equal(t, recordedEvent{"OnScheduled", Event{Scheduled: scheduled, Time: scheduled.Add(time.Second * 10)}}, e)
+			continue
+		}
+		assert.Equal(t, recordedEvent{"OnSkip", Event{Scheduled: scheduled, Time: scheduled.Add(time.Second * 10), Reason: SkipDropped}}, e)
+	}
+}
+
+func TestListenerTimerCoalesce(t *testing.T) {
+	listener := newRecordingListener()
+	timer, _ := slowReader(t, 2, WithDelivery(DeliverCoalesce), WithTimerListener(listener))
+	defer timer.Stop()
+
+	events := listener.next(t, 3)
+	assert.Equal(t, "OnSkip", events[2].method)
+	assert.Equal(t, epoch.Add(time.Minute), events[2].event.Scheduled)
+	assert.Equal(t, SkipCoalesced, events[2].event.Reason)
+	assert.Equal(t, epoch.Add(time.Minute*2), <-timer.Next())
+}
+
+func TestListenerTimerMisfire(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	listener := newRecordingListener()
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithMisfirePolicy(MisfireSkip), WithTimerListener(listener))
+	go timer.Start()
+	defer timer.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute * 3)
+	events := listener.next(t, 3)
+	for i, e := range events[:2] {
+		assert.Equal(t, "OnMisfire", e.method)
+		assert.Equal(t, epoch.Add(time.Minute*time.Duration(i+1)), e.event.Scheduled)
+	}
+	assert.Equal(t, "OnScheduled", events[2].method)
+	assert.Equal(t, epoch.Add(time.Minute*3), events[2].event.Scheduled)
+	assert.Equal(t, epoch.Add(time.Minute*3), <-timer.Next())
+}
+
+func TestAsyncListener(t *testing.T) {
+	recorder := newRecordingListener()
+	listener := NewAsyncListener(recorder, 10)
+	e := Event{JobID: "job", Scheduled: epoch}
+	listener.OnScheduled(e)
+	listener.OnStart(e)
+	listener.OnSuccess(e)
+	listener.OnError(e)
+	listener.OnSkip(e)
+	listener.OnMisfire(e)
+	listener.Close()
+	listener.Close()
+
+	var methods []string
+	for _, recorded := range recorder.next(t, 6) {
+		assert.Equal(t, e, recorded.event)
+		methods = append(methods, recorded.method)
+	}
+	assert.Equal(t, []string{"OnScheduled", "OnStart", "OnSuccess", "OnError", "OnSkip", "OnMisfire"}, methods)
+	assert.Empty(t, recorder.events)
+	assert.Zero(t, listener.Dropped())
+}
+
+// blockingListener signals on started when it is passed an event, and waits to be released before returning.
+type blockingListener struct {
+	NopListener
+	started, release chan struct{}
+	received         []time.Time
+}
+
+func (l *blockingListener) OnScheduled(e Event) {
+	l.started <- struct{}{}
+	<-l.release
+	l.received = append(l.received, e.Scheduled)
+}
+
+func TestAsyncListenerFull(t *testing.T) {
+	blocking := &blockingListener{started: make(chan struct{}, 10), release: make(chan struct{})}
+	listener := NewAsyncListener(blocking, 1)
+
+	// One event is being passed on and one is buffered, so the next is dropped rather than waiting
+	listener.OnScheduled(Event{Scheduled: epoch})
+	<-blocking.started
+	listener.OnScheduled(Event{Scheduled: epoch.Add(time.Minute)})
+	listener.OnScheduled(Event{Scheduled: epoch.Add(time.Minute * 2)})
+	assert.Equal(t, uint64(1), listener.Dropped())
+
+	close(blocking.release)
+	listener.Close()
+	assert.Equal(t, []time.Time{epoch, epoch.Add(time.Minute)}, blocking.received)
+}
+
+func TestNopListener(t *testing.T) {
+	var listener Listener = NopListener{}
+	listener.OnScheduled(Event{})
+	listener.OnStart(Event{})
+	listener.OnSuccess(Event{})
+	listener.OnError(Event{})
+	listener.OnSkip(Event{})
+	listener.OnMisfire(Event{})
+}
+
+func TestSkipReasonString(t *testing.T) {
+	cases := []struct {
+		reason   SkipReason
+		expected string
+	}{
+		{SkipOverlap, "overlap"},
+		{SkipLocked, "locked"},
+		{SkipCancelled, "cancelled"},
+		{SkipRemoved, "removed"},
+		{SkipDropped, "dropped"},
+		{SkipCoalesced, "coalesced"},
+		{SkipReason(0), "unknown"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.expected, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.reason.String())
+		})
+	}
+}
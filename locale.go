@@ -0,0 +1,181 @@
+package tokei
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale supplies the words and grammar used to describe expressions in a language.
+type Locale interface {
+	// Month names a month.
+	Month(month time.Month) string
+	// Weekday names a day of the week.
+	Weekday(day time.Weekday) string
+	// Ordinal formats a number as an ordinal, such as "2nd".
+	Ordinal(n int) string
+	// List joins words into a list, such as "1, 2 and 3".
+	List(words []string) string
+	// Phrase returns the template for part of a description.
+	// Templates contain placeholders such as {start}, which are documented on each Phrase.
+	Phrase(phrase Phrase) string
+}
+
+// Phrase identifies a part of a description which a Locale must translate.
+type Phrase int
+
+// Phrases used in descriptions. Placeholders available to each template are listed alongside it.
+// {values} is a list already joined by the locale, {nth} is the ordinal of {step}.
+const (
+	PhraseEveryMinute          Phrase = iota // no placeholders
+	PhraseAtMinute                           // {values}
+	PhraseAtMinutes                          // {values}
+	PhraseMinuteRange                        // {start}, {end}
+	PhraseMinuteRepeat                       // {step}, {nth}
+	PhraseMinuteRepeatFrom                   // {step}, {nth}, {start}
+	PhraseHour                               // {values}
+	PhraseHours                              // {values}
+	PhraseHourRange                          // {start}, {end}
+	PhraseHourRepeat                         // {step}, {nth}
+	PhraseHourRepeatFrom                     // {step}, {nth}, {start}
+	PhraseDayOfMonth                         // {values}
+	PhraseDayOfMonthRange                    // {start}, {end}
+	PhraseDayOfMonthRepeat                   // {step}, {nth}
+	PhraseDayOfMonthRepeatFrom               // {step}, {nth}, {start}
+	PhraseDayOfWeek                          // {values}
+	PhraseAndDayOfWeek                       // {values}, used when a day of the month is also described
+	PhraseMonth                              // {values}
+	PhraseNameRange                          // {start}, {end}, used for ranges of months and days of the week
+)
+
+// LocaleTable is a Locale built from translation tables.
+type LocaleTable struct {
+	// MonthNames lists the months from January.
+	MonthNames [12]string
+	// WeekdayNames lists the days of the week from Sunday.
+	WeekdayNames [7]string
+	// OrdinalFormat formats ordinals. Numbers are formatted as is if it is nil.
+	OrdinalFormat func(n int) string
+	// Separator joins all but the last two entries in a list.
+	Separator string
+	// Conjunction joins the last two entries in a list.
+	Conjunction string
+	// Phrases holds a template for every Phrase.
+	Phrases map[Phrase]string
+}
+
+// Month names a month.
+func (l *LocaleTable) Month(month time.Month) string {
+	return l.MonthNames[month-1]
+}
+
+// Weekday names a day of the week.
+func (l *LocaleTable) Weekday(day time.Weekday) string {
+	return l.WeekdayNames[day]
+}
+
+// Ordinal formats n using OrdinalFormat.
+func (l *LocaleTable) Ordinal(n int) string {
+	if l.OrdinalFormat == nil {
+		return strconv.Itoa(n)
+	}
+	return l.OrdinalFormat(n)
+}
+
+// List joins words using Separator and Conjunction.
+func (l *LocaleTable) List(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], l.Separator) + l.Conjunction + words[len(words)-1]
+}
+
+// Phrase returns the template for a phrase.
+func (l *LocaleTable) Phrase(phrase Phrase) string {
+	return l.Phrases[phrase]
+}
+
+// English describes expressions in English.
+var English Locale = &LocaleTable{
+	MonthNames: [12]string{
+		"January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December",
+	},
+	WeekdayNames:  [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	OrdinalFormat: ordinal,
+	Separator:     ", ",
+	Conjunction:   " and ",
+	Phrases: map[Phrase]string{
+		PhraseEveryMinute:          "Every minute",
+		PhraseAtMinute:             "At minute {values}",
+		PhraseAtMinutes:            "At minutes {values}",
+		PhraseMinuteRange:          "Every minute from {start} through {end}",
+		PhraseMinuteRepeat:         "Every {step} minutes",
+		PhraseMinuteRepeatFrom:     "Every {step} minutes from minute {start}",
+		PhraseHour:                 "past hour {values}",
+		PhraseHours:                "past hours {values}",
+		PhraseHourRange:            "past hours {start} through {end}",
+		PhraseHourRepeat:           "past every {step} hours",
+		PhraseHourRepeatFrom:       "past every {step} hours from hour {start}",
+		PhraseDayOfMonth:           "on day {values} of the month",
+		PhraseDayOfMonthRange:      "on day {start} through {end} of the month",
+		PhraseDayOfMonthRepeat:     "on every {nth} day of the month",
+		PhraseDayOfMonthRepeatFrom: "on every {nth} day of the month from day {start}",
+		PhraseDayOfWeek:            "on {values}",
+		PhraseAndDayOfWeek:         "and on {values}",
+		PhraseMonth:                "in {values}",
+		PhraseNameRange:            "{start} through {end}",
+	},
+}
+
+// German describes expressions in German.
+var German Locale = &LocaleTable{
+	MonthNames: [12]string{
+		"Januar", "Februar", "März", "April", "Mai", "Juni",
+		"Juli", "August", "September", "Oktober", "November", "Dezember",
+	},
+	WeekdayNames: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	OrdinalFormat: func(n int) string {
+		return strconv.Itoa(n) + "."
+	},
+	Separator:   ", ",
+	Conjunction: " und ",
+	Phrases: map[Phrase]string{
+		PhraseEveryMinute:          "Jede Minute",
+		PhraseAtMinute:             "In Minute {values}",
+		PhraseAtMinutes:            "In den Minuten {values}",
+		PhraseMinuteRange:          "Jede Minute von {start} bis {end}",
+		PhraseMinuteRepeat:         "Alle {step} Minuten",
+		PhraseMinuteRepeatFrom:     "Alle {step} Minuten ab Minute {start}",
+		PhraseHour:                 "nach Stunde {values}",
+		PhraseHours:                "nach den Stunden {values}",
+		PhraseHourRange:            "nach den Stunden {start} bis {end}",
+		PhraseHourRepeat:           "nach jeder {nth} Stunde",
+		PhraseHourRepeatFrom:       "nach jeder {nth} Stunde ab Stunde {start}",
+		PhraseDayOfMonth:           "am Tag {values} des Monats",
+		PhraseDayOfMonthRange:      "am Tag {start} bis {end} des Monats",
+		PhraseDayOfMonthRepeat:     "an jedem {nth} Tag des Monats",
+		PhraseDayOfMonthRepeatFrom: "an jedem {nth} Tag des Monats ab Tag {start}",
+		PhraseDayOfWeek:            "am {values}",
+		PhraseAndDayOfWeek:         "und am {values}",
+		PhraseMonth:                "im {values}",
+		PhraseNameRange:            "{start} bis {end}",
+	},
+}
+
+// ordinal formats n as an English ordinal such as 1st, 2nd or 11th.
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,58 @@
+package tokei
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeGerman(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"every minute", "* * * * *", "Jede Minute"},
+		{"hour repeat", "0 */2 * * *", "In Minute 0 nach jeder 2. Stunde"},
+		{"weekdays", "30 9,17 * * 1-5", "In Minute 30 nach den Stunden 9 und 17 am Montag bis Freitag"},
+		{"months", "*/15 * 1 3,6,12 *", "Alle 15 Minuten am Tag 1 des Monats im März, Juni und Dezember"},
+		{"complex", "10/5 3-5 1,2 7 2", "Alle 5 Minuten ab Minute 10 nach den Stunden 3 bis 5 am Tag 1 und 2 des Monats und am Dienstag im Juli"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ex, err := Parse(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ex.DescribeIn(German))
+		})
+	}
+}
+
+func TestLocaleTablePhrasesComplete(t *testing.T) {
+	for name, locale := range map[string]Locale{"English": English, "German": German} {
+		for phrase := PhraseEveryMinute; phrase <= PhraseNameRange; phrase++ {
+			assert.NotEmpty(t, locale.Phrase(phrase), "%s is missing phrase %d", name, phrase)
+		}
+	}
+}
+
+func TestLocaleTableList(t *testing.T) {
+	locale := &LocaleTable{Separator: "、", Conjunction: "と"}
+	assert.Equal(t, "", locale.List(nil))
+	assert.Equal(t, "1", locale.List([]string{"1"}))
+	assert.Equal(t, "1と2", locale.List([]string{"1", "2"}))
+	assert.Equal(t, "1、2と3", locale.List([]string{"1", "2", "3"}))
+}
+
+func TestLocaleTableOrdinal(t *testing.T) {
+	assert.Equal(t, "3", (&LocaleTable{}).Ordinal(3))
+	assert.Equal(t, "3.", German.Ordinal(3))
+}
+
+func TestOrdinal(t *testing.T) {
+	expected := map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 22: "22nd"}
+	for n, out := range expected {
+		assert.Equal(t, out, ordinal(n))
+	}
+}
@@ -0,0 +1,101 @@
+package tokei
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Locker makes sure that when several schedulers run the same jobs, such as replicas of a service, only one of
+// them runs each job at each scheduled time. Schedulers agree on the scheduled times, so the first to lock a
+// run gets to start it and the others skip it.
+type Locker interface {
+	// Lock tries to lock the run of a job at the scheduled time, reporting whether this scheduler holds it.
+	// Locks are never released, as each run only happens once, but can be forgotten once ttl has passed.
+	Lock(ctx context.Context, id string, scheduled time.Time, ttl time.Duration) (bool, error)
+}
+
+// LockerFunc adapts a func to Locker.
+type LockerFunc func(ctx context.Context, id string, scheduled time.Time, ttl time.Duration) (bool, error)
+
+// Lock calls f.
+func (f LockerFunc) Lock(ctx context.Context, id string, scheduled time.Time, ttl time.Duration) (bool, error) {
+	return f(ctx, id, scheduled, ttl)
+}
+
+// WithLocker locks each run of a job with locker before starting it, keeping locks for ttl. The TTL must be longer than the
+// difference between the clocks of the schedulers sharing the locker, so they don't forget a lock before another one
+// reaches the same time. If the locker fails, the run is skipped and the error passed to the error handler, as running it
+// could run it twice.
+func WithLocker(locker Locker, ttl time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.locker = locker
+		s.lockTTL = ttl
+	}
+}
+
+// lock takes the scheduler's lock on the run, reporting whether to go ahead with it.
+func (s *Scheduler) lock(r *run) bool {
+	ok, err := true, error(nil)
+	if s.locker != nil {
+		ok, err = s.locker.Lock(r.ctx, r.job.id, r.scheduled, s.lockTTL)
+	}
+	if err != nil {
+		ok = false
+		if s.onErr != nil {
+			s.onErr(r.job.id, err)
+		}
+	}
+
+	s.mu.Lock()
+	switch {
+	case ok:
+		r.job.stats.Runs++
+	case err == nil:
+		r.job.stats.LockedOut++
+	}
+	s.mu.Unlock()
+	if !ok && err == nil {
+		s.emit(skipEvent, r, Event{Reason: SkipLocked})
+	}
+	return ok
+}
+
+// MemoryLocker is a Locker for schedulers in the same process.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	clock Clock
+	locks map[memoryLock]time.Time
+}
+
+type memoryLock struct {
+	id        string
+	scheduled int64
+}
+
+// NewMemoryLocker creates a locker whose locks expire according to clock, which defaults to SystemClock if nil.
+func NewMemoryLocker(clock Clock) *MemoryLocker {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &MemoryLocker{clock: clock, locks: make(map[memoryLock]time.Time)}
+}
+
+// Lock locks the run of a job unless it is already locked. Expired locks are forgotten first.
+func (m *MemoryLocker) Lock(_ context.Context, id string, scheduled time.Time, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	for lock, expires := range m.locks {
+		if !expires.After(now) {
+			delete(m.locks, lock)
+		}
+	}
+
+	lock := memoryLock{id: id, scheduled: scheduled.UnixNano()}
+	if _, ok := m.locks[lock]; ok {
+		return false, nil
+	}
+	m.locks[lock] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,94 @@
+package tokei
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLocker checks the behaviour shared by every Locker. The clock must be the one the locker uses.
+func testLocker(t *testing.T, locker Locker, clock *FakeClock) {
+	ctx := context.Background()
+	ok, err := locker.Lock(ctx, "job", epoch, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = locker.Lock(ctx, "job", epoch, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Other jobs and times have their own locks
+	ok, err = locker.Lock(ctx, "other", epoch, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = locker.Lock(ctx, "job", epoch.Add(time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Locks are forgotten once they expire
+	clock.Advance(time.Minute)
+	ok, err = locker.Lock(ctx, "job", epoch, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestMemoryLocker(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	testLocker(t, NewMemoryLocker(clock), clock)
+}
+
+func TestSchedulerLocker(t *testing.T) {
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	locker := NewMemoryLocker(clock)
+	runs := make(chan time.Time, 10)
+
+	// Two replicas run the same job, but only one of them runs it each time
+	var replicas []*Scheduler
+	for i := 0; i < 2; i++ {
+		s := NewScheduler(WithSchedulerClock(clock), WithLocker(locker, time.Hour))
+		require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), recordRuns(clock, runs)))
+		go s.Start()
+		defer s.Stop(context.Background())
+		replicas = append(replicas, s)
+	}
+
+	for i := 1; i <= 3; i++ {
+		clock.BlockUntil(2)
+		clock.Advance(time.Minute)
+		<-runs
+	}
+	var stats JobStats
+	for _, s := range replicas {
+		waitIdle(t, s, "job")
+		replica, _ := s.Stats("job")
+		stats.Runs += replica.Runs
+		stats.LockedOut += replica.LockedOut
+	}
+	assert.Empty(t, runs)
+	assert.Equal(t, JobStats{Runs: 3, LockedOut: 3}, stats)
+}
+
+func TestSchedulerLockerError(t *testing.T) {
+	failed := make(chan error, 1)
+	locker := LockerFunc(func(context.Context, string, time.Time, time.Duration) (bool, error) {
+		return false, errors.New("unavailable")
+	})
+	s, clock := startScheduler(t, WithLocker(locker, time.Hour), WithErrorHandler(func(id string, err error) {
+		failed <- err
+	}))
+	runs := make(chan time.Time, 10)
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), recordRuns(clock, runs)))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.EqualError(t, <-failed, "unavailable")
+	waitIdle(t, s, "job")
+	assert.Empty(t, runs)
+
+	stats, _ := s.Stats("job")
+	assert.Equal(t, JobStats{}, stats)
+}
@@ -0,0 +1,117 @@
+package tokei
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// MarshalText implements encoding.TextMarshaler using the canonical form of the expression.
+func (c *CronExpression) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler by parsing the text as an expression.
+func (c *CronExpression) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*c = *parsed
+	return nil
+}
+
+// errCalendarSchedule is returned when serializing a schedule with a calendar, which can't be serialized.
+var errCalendarSchedule = errors.New("schedules with a calendar can't be serialized")
+
+// scheduleConfig is the serialized form of a Schedule. A limit on the number of occurrences is kept as the bounds
+// it was converted to.
+type scheduleConfig struct {
+	Expression *CronExpression `json:"expression" yaml:"expression"`
+	Location   string          `json:"location,omitempty" yaml:"location,omitempty"`
+	NotBefore  *time.Time      `json:"notBefore,omitempty" yaml:"notBefore,omitempty"`
+	NotAfter   *time.Time      `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+}
+
+// config converts the schedule to its serialized form.
+func (s *Schedule) config() (scheduleConfig, error) {
+	if s.calendar != nil {
+		return scheduleConfig{}, errCalendarSchedule
+	}
+	// Locations are stored by name, so those which can't be loaded by name, such as fixed zones, can't be restored.
+	location := s.location.String()
+	if _, err := time.LoadLocation(location); err != nil {
+		return scheduleConfig{}, errors.New("location " + location + " can't be serialized: " + err.Error())
+	}
+	config := scheduleConfig{
+		Expression: s.expression,
+		Location:   location,
+	}
+	if !s.notBefore.IsZero() {
+		config.NotBefore = &s.notBefore
+	}
+	if !s.notAfter.IsZero() {
+		config.NotAfter = &s.notAfter
+	}
+	return config, nil
+}
+
+// load validates a serialized schedule and replaces s with it.
+// The location must be an IANA name such as "Europe/Berlin" and defaults to UTC.
+func (s *Schedule) load(config scheduleConfig) error {
+	if config.Expression == nil {
+		return errors.New("schedule is missing an expression")
+	}
+	location := time.UTC
+	if config.Location != "" {
+		var err error
+		location, err = time.LoadLocation(config.Location)
+		if err != nil {
+			return err
+		}
+	}
+	var opts []ScheduleOption
+	if config.NotBefore != nil {
+		opts = append(opts, NotBefore(*config.NotBefore))
+	}
+	if config.NotAfter != nil {
+		opts = append(opts, NotAfter(*config.NotAfter))
+	}
+	*s = *NewSchedule(location, config.Expression, opts...)
+	return nil
+}
+
+// MarshalJSON encodes the schedule as its expression, location name and any bounds.
+// Schedules with a calendar, or in a location which can't be loaded by name, return an error.
+func (s *Schedule) MarshalJSON() ([]byte, error) {
+	config, err := s.config()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(config)
+}
+
+// UnmarshalJSON decodes a schedule of the form {"expression": "*/5 * * * *", "location": "Europe/Berlin"}.
+// The bounds are optional, as in {"expression": "0 9 * * *", "notAfter": "2021-12-31T00:00:00Z"}.
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	var config scheduleConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	return s.load(config)
+}
+
+// MarshalYAML encodes the schedule in the same form as MarshalJSON.
+// It satisfies the Marshaler interface used by the common YAML packages without depending on them.
+func (s *Schedule) MarshalYAML() (interface{}, error) {
+	return s.config()
+}
+
+// UnmarshalYAML decodes the schedule in the same form as UnmarshalJSON.
+func (s *Schedule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var config scheduleConfig
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+	return s.load(config)
+}
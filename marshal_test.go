@@ -0,0 +1,143 @@
+package tokei
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpressionText(t *testing.T) {
+	ex, err := Parse("0,15,30,45 * * * *")
+	require.NoError(t, err)
+
+	text, err := ex.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "*/15 * * * *", string(text))
+
+	var out CronExpression
+	require.NoError(t, out.UnmarshalText(text))
+	assert.Equal(t, ex.String(), out.String())
+
+	assert.Error(t, out.UnmarshalText([]byte("*/0 * * * *")))
+}
+
+func TestExpressionJSON(t *testing.T) {
+	var config struct {
+		Schedule *CronExpression `json:"schedule"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"schedule": "*/5 * * * *"}`), &config))
+	assert.Equal(t, "*/5 * * * *", config.Schedule.String())
+
+	out, err := json.Marshal(config)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"schedule": "*/5 * * * *"}`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"schedule": "blah"}`), &config))
+}
+
+func TestScheduleJSON(t *testing.T) {
+	var sched Schedule
+	require.NoError(t, json.Unmarshal([]byte(`{"expression": "0 9 * * 1-5", "location": "Europe/Berlin"}`), &sched))
+	assert.Equal(t, "0 9 * * 1-5", sched.Expression().String())
+	assert.Equal(t, "Europe/Berlin", sched.Location().String())
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 1, 1, 9, 0, 0, 0, berlin), sched.NextFrom(time.Date(2018, 1, 1, 0, 0, 0, 0, berlin)))
+
+	out, err := json.Marshal(&sched)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"expression": "0 9 * * 1-5", "location": "Europe/Berlin"}`, string(out))
+}
+
+func TestScheduleJSONDefaultLocation(t *testing.T) {
+	var sched Schedule
+	require.NoError(t, json.Unmarshal([]byte(`{"expression": "* * * * *"}`), &sched))
+	assert.Equal(t, time.UTC, sched.Location())
+}
+
+func TestScheduleJSONBounds(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+
+	// Bounds are kept, including those from a limit on the number of occurrences
+	sched := NewScheduleUTC(ex, NotBefore(epoch.Add(time.Hour)), MaxOccurrences(3, epoch))
+	out, err := json.Marshal(sched)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"expression": "0 * * * *", "location": "UTC", "notBefore": "1970-01-01T01:00:00Z",
+		"notAfter": "1970-01-01T03:00:00Z"}`, string(out))
+
+	var decoded Schedule
+	require.NoError(t, json.Unmarshal(out, &decoded))
+	assert.Equal(t, sched.ProjectFrom(epoch, 5), decoded.ProjectFrom(epoch, 5))
+
+	// Calendars can't be serialized
+	_, err = json.Marshal(NewScheduleUTC(ex, WithCalendar(NewDateSet(epoch))))
+	assert.Error(t, err)
+}
+
+func TestScheduleJSONFixedZone(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+
+	// Fixed zones can't be loaded by name, so can't be decoded again
+	_, err = json.Marshal(NewSchedule(time.FixedZone("UTC+2", 7200), ex))
+	assert.Error(t, err)
+
+	out, err := json.Marshal(NewSchedule(time.Local, ex))
+	require.NoError(t, err)
+	var decoded Schedule
+	require.NoError(t, json.Unmarshal(out, &decoded))
+}
+
+func TestScheduleJSONErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"not json", `blah`},
+		{"missing expression", `{"location": "UTC"}`},
+		{"bad expression", `{"expression": "* * *"}`},
+		{"bad location", `{"expression": "* * * * *", "location": "Mars/Olympus_Mons"}`},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var sched Schedule
+			assert.Error(t, json.Unmarshal([]byte(test.input), &sched))
+		})
+	}
+}
+
+func TestScheduleYAML(t *testing.T) {
+	var config struct {
+		Schedule *Schedule `yaml:"schedule"`
+	}
+	input := `
+schedule:
+  expression: 0 9 * * 1-5
+  location: Europe/Berlin
+  notAfter: 2018-12-31T00:00:00Z
+`
+	require.NoError(t, yaml.Unmarshal([]byte(input), &config))
+	assert.Equal(t, "0 9 * * 1-5", config.Schedule.Expression().String())
+	assert.Equal(t, "Europe/Berlin", config.Schedule.Location().String())
+	assert.True(t, config.Schedule.NextFrom(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero())
+
+	out, err := yaml.Marshal(config)
+	require.NoError(t, err)
+	assert.YAMLEq(t, input, string(out))
+
+	assert.Error(t, yaml.Unmarshal([]byte("schedule: {expression: '* * *'}"), &config))
+	assert.Error(t, yaml.Unmarshal([]byte("schedule: {location: UTC}"), &config))
+
+	ex, err := Parse("*/5 * * * *")
+	require.NoError(t, err)
+	config.Schedule = NewScheduleUTC(ex, WithCalendar(NewDateSet(epoch)))
+	_, err = yaml.Marshal(config)
+	assert.Error(t, err)
+}
@@ -0,0 +1,197 @@
+package tokei
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Metrics records measurements of a Scheduler's jobs or ScheduleTimers, such as to export them to a monitoring system.
+// Jobs are identified by their ID, and timers by the name given to WithTimerMetrics.
+// Methods are called from several goroutines, sometimes while the scheduler is locked, so must be safe for
+// concurrent use and return quickly.
+type Metrics interface {
+	// ObserveLag records how long after its scheduled time a job started or a timer fired.
+	ObserveLag(id string, lag time.Duration)
+	// ObserveRun records how long an attempt at running a job took, and the error it returned if it failed.
+	ObserveRun(id string, duration time.Duration, err error)
+	// Skipped records a scheduled time which fired but wasn't run or delivered.
+	Skipped(id string, reason SkipReason)
+	// Misfired records a scheduled time which was missed.
+	Misfired(id string)
+	// SetNextFire records when a job or timer next fires, or the zero time if it never will again.
+	SetNextFire(id string, next time.Time)
+	// AddActiveTimers adds delta to the number of timers which are running.
+	AddActiveTimers(delta int)
+}
+
+// WithMetrics records measurements of the scheduler's jobs in m.
+func WithMetrics(m Metrics) SchedulerOption {
+	return func(s *Scheduler) {
+		s.metrics = m
+		s.listeners = append(s.listeners, metricsListener{metrics: m})
+	}
+}
+
+// WithTimerMetrics records measurements of the timer in m, using name to tell it apart from other timers.
+func WithTimerMetrics(name string, m Metrics) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.name = name
+		st.metrics = m
+		st.listeners = append(st.listeners, metricsListener{metrics: m, id: name})
+	}
+}
+
+// metricsListener passes the events of a scheduler or timer on to its metrics.
+type metricsListener struct {
+	metrics Metrics
+	// id replaces the job ID of events, which is needed for timers.
+	id string
+}
+
+func (l metricsListener) jobID(e Event) string {
+	if l.id != "" {
+		return l.id
+	}
+	return e.JobID
+}
+
+// OnScheduled records the lag of timers. Jobs' lag is recorded when they start, as they may wait for a worker.
+func (l metricsListener) OnScheduled(e Event) {
+	if e.JobID == "" {
+		l.metrics.ObserveLag(l.jobID(e), e.Time.Sub(e.Scheduled))
+	}
+}
+
+// OnStart records the lag of a job's first attempt.
+func (l metricsListener) OnStart(e Event) {
+	if e.Attempt == 1 {
+		l.metrics.ObserveLag(l.jobID(e), e.Time.Sub(e.Scheduled))
+	}
+}
+
+// OnSuccess records the run.
+func (l metricsListener) OnSuccess(e Event) {
+	l.metrics.ObserveRun(l.jobID(e), e.Duration, nil)
+}
+
+// OnError records the failed run.
+func (l metricsListener) OnError(e Event) {
+	l.metrics.ObserveRun(l.jobID(e), e.Duration, e.Err)
+}
+
+// OnSkip records the skip.
+func (l metricsListener) OnSkip(e Event) {
+	l.metrics.Skipped(l.jobID(e), e.Reason)
+}
+
+// OnMisfire records the misfire.
+func (l metricsListener) OnMisfire(e Event) {
+	l.metrics.Misfired(l.jobID(e))
+}
+
+// ExpvarMetrics keeps metrics in expvar variables. It is itself an expvar.Var, so can be published with
+// expvar.Publish, which makes the metrics available as JSON from /debug/vars. Durations are in seconds and
+// times are Unix timestamps.
+type ExpvarMetrics struct {
+	mu     sync.Mutex
+	timers expvar.Int
+	jobs   expvar.Map
+}
+
+// NewExpvarMetrics creates metrics with nothing recorded yet.
+func NewExpvarMetrics() *ExpvarMetrics {
+	return &ExpvarMetrics{}
+}
+
+// job returns the variables of a job or timer, creating them if needed.
+func (m *ExpvarMetrics) job(id string) *expvar.Map {
+	if vars, ok := m.jobs.Get(id).(*expvar.Map); ok {
+		return vars
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if vars, ok := m.jobs.Get(id).(*expvar.Map); ok {
+		return vars
+	}
+	vars := new(expvar.Map)
+	m.jobs.Set(id, vars)
+	return vars
+}
+
+// max sets the float variable key of vars to v if it's larger.
+func (m *ExpvarMetrics) max(vars *expvar.Map, key string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := vars.Get(key).(*expvar.Float); ok && current.Value() >= v {
+		return
+	}
+	f := new(expvar.Float)
+	f.Set(v)
+	vars.Set(key, f)
+}
+
+// ObserveLag counts the fire in fires, and adds the lag to lagSeconds and maxLagSeconds.
+func (m *ExpvarMetrics) ObserveLag(id string, lag time.Duration) {
+	vars := m.job(id)
+	vars.Add("fires", 1)
+	vars.AddFloat("lagSeconds", lag.Seconds())
+	m.max(vars, "maxLagSeconds", lag.Seconds())
+}
+
+// ObserveRun counts the run in runs, and failures if it failed, and adds its duration to runSeconds and maxRunSeconds.
+func (m *ExpvarMetrics) ObserveRun(id string, duration time.Duration, err error) {
+	vars := m.job(id)
+	vars.Add("runs", 1)
+	if err != nil {
+		vars.Add("failures", 1)
+	}
+	vars.AddFloat("runSeconds", duration.Seconds())
+	m.max(vars, "maxRunSeconds", duration.Seconds())
+}
+
+// Skipped counts the skip in skipped, and by its reason in skipped.<reason>.
+func (m *ExpvarMetrics) Skipped(id string, reason SkipReason) {
+	vars := m.job(id)
+	vars.Add("skipped", 1)
+	vars.Add("skipped."+reason.String(), 1)
+}
+
+// Misfired counts the misfire in misfired.
+func (m *ExpvarMetrics) Misfired(id string) {
+	m.job(id).Add("misfired", 1)
+}
+
+// SetNextFire sets nextFire, or removes it if next is the zero time.
+func (m *ExpvarMetrics) SetNextFire(id string, next time.Time) {
+	vars := m.job(id)
+	if next.IsZero() {
+		vars.Delete("nextFire")
+		return
+	}
+	v := new(expvar.Int)
+	v.Set(next.Unix())
+	vars.Set("nextFire", v)
+}
+
+// AddActiveTimers adds delta to activeTimers.
+func (m *ExpvarMetrics) AddActiveTimers(delta int) {
+	m.timers.Add(int64(delta))
+}
+
+// ActiveTimers returns the number of timers which are running.
+func (m *ExpvarMetrics) ActiveTimers() int64 {
+	return m.timers.Value()
+}
+
+// Job returns the variables recorded for a job or timer, or nil if nothing has been recorded for it.
+func (m *ExpvarMetrics) Job(id string) *expvar.Map {
+	vars, _ := m.jobs.Get(id).(*expvar.Map)
+	return vars
+}
+
+// String returns the metrics as JSON, so that ExpvarMetrics is an expvar.Var.
+func (m *ExpvarMetrics) String() string {
+	return fmt.Sprintf(`{"activeTimers": %s, "jobs": %s}`, m.timers.String(), m.jobs.String())
+}
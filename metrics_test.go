@@ -0,0 +1,116 @@
+package tokei
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vars returns the variables recorded for a job or timer, decoded from JSON.
+func vars(t *testing.T, m *ExpvarMetrics, id string) map[string]interface{} {
+	var decoded struct {
+		ActiveTimers int64                             `json:"activeTimers"`
+		Jobs         map[string]map[string]interface{} `json:"jobs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(m.String()), &decoded))
+	assert.Equal(t, m.ActiveTimers(), decoded.ActiveTimers)
+	return decoded.Jobs[id]
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	m := NewExpvarMetrics()
+	var _ expvar.Var = m
+	assert.Nil(t, m.Job("job"))
+
+	m.ObserveLag("job", time.Second)
+	m.ObserveLag("job", time.Second*3)
+	m.ObserveLag("job", time.Second*2)
+	m.ObserveRun("job", time.Second*2, nil)
+	m.ObserveRun("job", time.Second, errors.New("failed"))
+	m.Skipped("job", SkipOverlap)
+	m.Skipped("job", SkipLocked)
+	m.Skipped("job", SkipOverlap)
+	m.Misfired("job")
+	m.SetNextFire("job", epoch.Add(time.Minute))
+	m.AddActiveTimers(2)
+	m.AddActiveTimers(-1)
+
+	assert.Equal(t, map[string]interface{}{
+		"fires":           float64(3),
+		"lagSeconds":      float64(6),
+		"maxLagSeconds":   float64(3),
+		"runs":            float64(2),
+		"failures":        float64(1),
+		"runSeconds":      float64(3),
+		"maxRunSeconds":   float64(2),
+		"skipped":         float64(3),
+		"skipped.overlap": float64(2),
+		"skipped.locked":  float64(1),
+		"misfired":        float64(1),
+		"nextFire":        float64(60),
+	}, vars(t, m, "job"))
+	assert.Equal(t, int64(1), m.ActiveTimers())
+	assert.NotNil(t, m.Job("job"))
+
+	m.SetNextFire("job", time.Time{})
+	assert.NotContains(t, vars(t, m, "job"), "nextFire")
+}
+
+func TestSchedulerMetrics(t *testing.T) {
+	m := NewExpvarMetrics()
+	s, clock := startScheduler(t, WithMetrics(m))
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(context.Context) error {
+		clock.Advance(time.Second)
+		return errors.New("failed")
+	}))
+	assert.Equal(t, float64(60), vars(t, m, "job")["nextFire"])
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute + time.Second*5))
+	waitIdle(t, s, "job")
+	assert.Equal(t, map[string]interface{}{
+		"fires":         float64(1),
+		"lagSeconds":    float64(5),
+		"maxLagSeconds": float64(5),
+		"runs":          float64(1),
+		"failures":      float64(1),
+		"runSeconds":    float64(1),
+		"maxRunSeconds": float64(1),
+		"nextFire":      float64(120),
+	}, vars(t, m, "job"))
+
+	s.Remove("job")
+	assert.NotContains(t, vars(t, m, "job"), "nextFire")
+}
+
+func TestTimerMetrics(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	m := NewExpvarMetrics()
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithTimerMetrics("timer", m))
+	go timer.Start()
+
+	clock.BlockUntil(1)
+	assert.Equal(t, int64(1), m.ActiveTimers())
+	assert.Equal(t, float64(60), vars(t, m, "timer")["nextFire"])
+
+	clock.Set(epoch.Add(time.Minute + time.Second*2))
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+	clock.BlockUntil(1)
+	recorded := vars(t, m, "timer")
+	assert.Equal(t, float64(1), recorded["fires"])
+	assert.Equal(t, float64(2), recorded["lagSeconds"])
+	assert.Equal(t, float64(120), recorded["nextFire"])
+
+	timer.Stop()
+	for range timer.Next() {
+	}
+	assert.Equal(t, int64(0), m.ActiveTimers())
+}
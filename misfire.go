@@ -0,0 +1,150 @@
+package tokei
+
+import "time"
+
+// MisfirePolicy decides what a ScheduleTimer does with times it noticed too late, such as when the machine
+// was suspended, the clock jumped forwards, or the process wasn't running.
+type MisfirePolicy int
+
+// Types of MisfirePolicy
+const (
+	// MisfireFireOnce fires once for the latest missed time, unless a time which isn't late is also due.
+	// This is Quartz's "fire once now".
+	MisfireFireOnce MisfirePolicy = iota
+	// MisfireSkip doesn't fire for missed times. This is Quartz's "do nothing".
+	MisfireSkip
+	// MisfireFireAll fires for every missed time, in order. This is Quartz's "ignore misfire policy".
+	// After a long outage only the latest maxMissed times are fired.
+	MisfireFireAll
+)
+
+// maxMissed limits how many missed times are handled at once, so that a long outage doesn't build up
+// a time for every minute of it. The latest times are kept, as they're the ones which still matter.
+const maxMissed = 1000
+
+// WithMisfirePolicy sets what the timer does with missed times. It defaults to MisfireFireOnce.
+func WithMisfirePolicy(policy MisfirePolicy) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.misfirePolicy = policy
+	}
+}
+
+// WithMisfireThreshold sets how late the timer can notice a time before it counts as missed.
+func WithMisfireThreshold(d time.Duration) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.misfireThreshold = d
+	}
+}
+
+// WithMisfireHandler calls f with the times the timer missed, before the misfire policy is applied.
+func WithMisfireHandler(f func(missed []time.Time)) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.misfireHandler = f
+	}
+}
+
+// WithLastFired tells the timer the scheduled time it last fired for before it was started, usually loaded
+// from storage. Times between then and starting the timer are handled by the misfire policy.
+func WithLastFired(t time.Time) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.lastFired = t
+	}
+}
+
+// WithFireHandler calls f after every tick is received, so that the scheduled time can be stored and passed
+// to WithLastFired when the timer is next started.
+func WithFireHandler(f func(tick Tick)) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.fireHandler = f
+	}
+}
+
+// catchUp fires for any times between the last time the timer fired and now.
+func (st *ScheduleTimer) catchUp() bool {
+	if st.lastFired.IsZero() {
+		return true
+	}
+	now := st.clock.Now()
+	if latest := st.schedule.PrevFrom(now); latest.IsZero() || !latest.After(st.lastFired) {
+		return true
+	}
+	first := st.schedule.NextFrom(after(st.lastFired))
+	return st.fire(st.due(first, now), first, now)
+}
+
+// apply returns which of the missed times should still fire.
+func (p MisfirePolicy) apply(missed []time.Time, onTime bool) []time.Time {
+	switch p {
+	case MisfireSkip:
+		return nil
+	case MisfireFireAll:
+		return missed
+	default:
+		if onTime || len(missed) == 0 {
+			return nil
+		}
+		return missed[len(missed)-1:]
+	}
+}
+
+// due returns every scheduled time from next up to now, or the latest maxMissed of them.
+func (st *ScheduleTimer) due(next, now time.Time) []time.Time {
+	return dueTimes(st.schedule, next, now)
+}
+
+// dueTimes returns the scheduled times from next, which must match, up to now. If there are more than maxMissed,
+// only the latest maxMissed are returned, found by searching backwards from now rather than walking every time.
+func dueTimes(schedule *Schedule, next, now time.Time) []time.Time {
+	due := []time.Time{next}
+	for t := schedule.NextFrom(after(next)); !t.IsZero() && !t.After(now); t = schedule.NextFrom(after(t)) {
+		if len(due) == maxMissed {
+			return latestDue(schedule, next, now)
+		}
+		due = append(due, t)
+	}
+	return due
+}
+
+// latestDue returns the latest maxMissed scheduled times from next up to now, in order.
+func latestDue(schedule *Schedule, next, now time.Time) []time.Time {
+	latest := make([]time.Time, maxMissed)
+	i := len(latest)
+	for t := schedule.PrevFrom(now); i > 0 && !t.IsZero() && !t.Before(next); t = schedule.PrevFrom(t.Add(-time.Nanosecond)) {
+		i--
+		latest[i] = t
+	}
+	return latest[i:]
+}
+
+// fire delivers the due times, applying the misfire policy to those which were noticed too late.
+// The first due time was expected to fire at planned, which includes any jitter.
+func (st *ScheduleTimer) fire(due []time.Time, planned, now time.Time) bool {
+	st.lastFired = due[len(due)-1]
+	var missed, onTime []time.Time
+	for i, scheduled := range due {
+		expected := scheduled
+		if i == 0 {
+			expected = planned
+		}
+		if now.Sub(expected) > st.misfireThreshold {
+			missed = append(missed, scheduled)
+			continue
+		}
+		onTime = append(onTime, scheduled)
+	}
+	if len(missed) > 0 && st.misfireHandler != nil {
+		st.misfireHandler(missed)
+	}
+	for _, scheduled := range missed {
+		st.emit(misfireEvent, scheduled, 0)
+	}
+
+	fired := now.In(st.schedule.location)
+	for _, scheduled := range append(st.misfirePolicy.apply(missed, len(onTime) > 0), onTime...) {
+		st.emit(scheduledEvent, scheduled, 0)
+		if !st.deliver(Tick{Scheduled: scheduled, Fired: fired}) {
+			return false
+		}
+	}
+	return true
+}
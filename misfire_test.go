@@ -0,0 +1,211 @@
+package tokei
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMisfirePolicy(t *testing.T) {
+	missed := []time.Time{epoch, epoch.Add(time.Minute)}
+	cases := []struct {
+		name     string
+		policy   MisfirePolicy
+		onTime   bool
+		expected []time.Time
+	}{
+		{"fire once", MisfireFireOnce, false, missed[1:]},
+		{"fire once with on time", MisfireFireOnce, true, nil},
+		{"skip", MisfireSkip, false, nil},
+		{"fire all", MisfireFireAll, false, missed},
+		{"fire all with on time", MisfireFireAll, true, missed},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.policy.apply(missed, test.onTime))
+		})
+	}
+	assert.Nil(t, MisfireFireOnce.apply(nil, false))
+}
+
+// suspendTimer starts an hourly timer at 00:30 and suspends the machine until 03:10, missing 01:00 to 03:00.
+func suspendTimer(t *testing.T, opts ...TimerOption) (*ScheduleTimer, *FakeClock) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Minute * 30))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(opts...)
+	go timer.Start()
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour*3 + time.Minute*10))
+	return timer, clock
+}
+
+func TestTimerMisfireFireOnce(t *testing.T) {
+	var missed []time.Time
+	timer, clock := suspendTimer(t, WithMisfireHandler(func(times []time.Time) {
+		missed = times
+	}))
+	defer timer.Stop()
+
+	tick := <-timer.Ticks()
+	assert.Equal(t, Tick{Scheduled: epoch.Add(time.Hour * 3), Fired: epoch.Add(time.Hour*3 + time.Minute*10)}, tick)
+	assert.Equal(t, []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2), epoch.Add(time.Hour * 3)}, missed)
+
+	// Then carries on as normal
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour * 4))
+	assert.Equal(t, epoch.Add(time.Hour*4), <-timer.Next())
+}
+
+func TestTimerMisfireSkip(t *testing.T) {
+	timer, clock := suspendTimer(t, WithMisfirePolicy(MisfireSkip))
+	defer timer.Stop()
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour * 4))
+	assert.Equal(t, epoch.Add(time.Hour*4), <-timer.Next())
+}
+
+func TestTimerMisfireFireAll(t *testing.T) {
+	timer, _ := suspendTimer(t, WithMisfirePolicy(MisfireFireAll))
+	defer timer.Stop()
+
+	for hour := time.Duration(1); hour <= 3; hour++ {
+		assert.Equal(t, epoch.Add(time.Hour*hour), <-timer.Next())
+	}
+}
+
+func TestTimerMisfireThreshold(t *testing.T) {
+	// Ten minutes late is within the threshold, so 03:00 fires normally and only 01:00 and 02:00 are missed.
+	var missed []time.Time
+	timer, _ := suspendTimer(t, WithMisfirePolicy(MisfireSkip), WithMisfireThreshold(time.Minute*15), WithMisfireHandler(func(times []time.Time) {
+		missed = times
+	}))
+	defer timer.Stop()
+
+	assert.Equal(t, epoch.Add(time.Hour*3), <-timer.Next())
+	assert.Equal(t, []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2)}, missed)
+}
+
+func TestDueTimes(t *testing.T) {
+	schedule := mustSchedule(t, "* * * * *")
+	assert.Equal(t, []time.Time{epoch, epoch.Add(time.Minute), epoch.Add(time.Minute * 2)},
+		dueTimes(schedule, epoch, epoch.Add(time.Minute*2+time.Second)))
+
+	// Only the latest are kept after a long outage
+	year := epoch.AddDate(1, 0, 0)
+	due := dueTimes(schedule, epoch, year.Add(time.Second))
+	require.Len(t, due, maxMissed)
+	assert.Equal(t, year.Add(-time.Minute*(maxMissed-1)), due[0])
+	assert.Equal(t, year, due[len(due)-1])
+	for i := 1; i < len(due); i++ {
+		assert.Equal(t, time.Minute, due[i].Sub(due[i-1]))
+	}
+}
+
+func TestTimerMisfireLongOutage(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	var missed []time.Time
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithMisfireHandler(func(times []time.Time) {
+		missed = times
+	}))
+	go timer.Start()
+	defer timer.Stop()
+
+	// Suspended for a year
+	clock.BlockUntil(1)
+	year := epoch.AddDate(1, 0, 0)
+	clock.Set(year.Add(time.Minute * 5))
+	// The last two times are within the threshold, so aren't missed
+	assert.Equal(t, year.Add(time.Minute*4), <-timer.Next())
+	assert.Equal(t, year.Add(time.Minute*5), <-timer.Next())
+	require.Len(t, missed, maxMissed-2)
+	assert.Equal(t, year.Add(time.Minute*3), missed[len(missed)-1])
+}
+
+func TestTimerCheckInterval(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Minute * 30))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithCheckInterval(time.Minute * 10))
+	go timer.Start()
+	defer timer.Stop()
+
+	// The timer wakes every ten minutes to check the clock, so it notices it being changed.
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute * 10)
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute * 59))
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Hour), <-timer.Next())
+}
+
+func TestTimerCatchUp(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		policy   MisfirePolicy
+		expected []time.Time
+	}{
+		{"fire once", MisfireFireOnce, []time.Time{epoch.Add(time.Hour * 3)}},
+		{"skip", MisfireSkip, nil},
+		{"fire all", MisfireFireAll, []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2), epoch.Add(time.Hour * 3)}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			// The process last fired at midnight and was restarted at 03:30.
+			clock := NewFakeClock(epoch.Add(time.Hour*3 + time.Minute*30))
+			var stored []time.Time
+			timer := NewScheduleUTC(ex, WithClock(clock)).Timer(
+				WithMisfirePolicy(test.policy),
+				WithLastFired(epoch),
+				WithFireHandler(func(tick Tick) {
+					stored = append(stored, tick.Scheduled)
+				}),
+			)
+			go timer.Start()
+			defer timer.Stop()
+
+			var fired []time.Time
+			for range test.expected {
+				fired = append(fired, <-timer.Next())
+			}
+			assert.Equal(t, test.expected, fired)
+
+			// Then carries on as normal
+			clock.BlockUntil(1)
+			clock.Set(epoch.Add(time.Hour*4 + time.Second*10))
+			assert.Equal(t, epoch.Add(time.Hour*4), <-timer.Next())
+
+			clock.BlockUntil(1)
+			assert.Equal(t, append(test.expected, epoch.Add(time.Hour*4)), stored)
+		})
+	}
+}
+
+func TestTimerCatchUpNothingMissed(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Minute * 30))
+	missed := false
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithLastFired(epoch), WithMisfireHandler(func([]time.Time) {
+		missed = true
+	}))
+	go timer.Start()
+	defer timer.Stop()
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour + time.Second*10))
+	assert.Equal(t, epoch.Add(time.Hour), <-timer.Next())
+	assert.False(t, missed)
+}
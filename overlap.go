@@ -0,0 +1,66 @@
+package tokei
+
+import "time"
+
+// OverlapPolicy decides what a Scheduler does when a job is due while an earlier run of it is still going.
+// The policies match the concurrency policies of a Kubernetes CronJob.
+type OverlapPolicy int
+
+// Types of OverlapPolicy
+const (
+	// OverlapAllow starts the new run alongside the earlier one. This is the default.
+	OverlapAllow OverlapPolicy = iota
+	// OverlapForbid skips the new run. This is Kubernetes' "Forbid".
+	OverlapForbid
+	// OverlapQueue starts the new run once the earlier one finishes. Only one run waits at a time, and any
+	// more which are due while it waits are skipped.
+	OverlapQueue
+	// OverlapReplace cancels the earlier run's context and starts the new run straight away. This is Kubernetes' "Replace".
+	OverlapReplace
+)
+
+// WithOverlapPolicy sets what the scheduler does when the job is due while it's still running.
+func WithOverlapPolicy(policy OverlapPolicy) JobOption {
+	return func(j *job) {
+		j.overlap = policy
+	}
+}
+
+// trigger submits a run of a job which is due, applying its overlap policy if it's still running.
+func (s *Scheduler) trigger(j *job, scheduled time.Time) {
+	s.record(scheduledEvent, j, scheduled, 0)
+	if j.running > 0 {
+		switch j.overlap {
+		case OverlapForbid:
+			j.stats.Skipped++
+			s.record(skipEvent, j, scheduled, SkipOverlap)
+			return
+		case OverlapQueue:
+			if !j.queued.IsZero() {
+				j.stats.Skipped++
+				s.record(skipEvent, j, scheduled, SkipOverlap)
+				return
+			}
+			j.queued = scheduled
+			j.stats.Queued++
+			return
+		case OverlapReplace:
+			j.cancel()
+			j.stats.Replaced++
+		}
+	}
+	s.submit(j, scheduled)
+}
+
+// release records that a run of a job has finished or been dropped, and submits the run waiting for it if there is one.
+func (s *Scheduler) release(j *job) {
+	j.running--
+	if j.queued.IsZero() || j.running > 0 {
+		return
+	}
+	scheduled := j.queued
+	j.queued = time.Time{}
+	if !s.stopped && s.jobs[j.id] == j {
+		s.submit(j, scheduled)
+	}
+}
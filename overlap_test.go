@@ -0,0 +1,118 @@
+package tokei
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowJob is a job which runs until it is released or cancelled.
+type slowJob struct {
+	started   chan context.Context
+	cancelled chan error
+	release   chan struct{}
+}
+
+func newSlowJob() *slowJob {
+	return &slowJob{
+		started:   make(chan context.Context, 10),
+		cancelled: make(chan error, 10),
+		release:   make(chan struct{}),
+	}
+}
+
+func (j *slowJob) run(ctx context.Context) error {
+	j.started <- ctx
+	select {
+	case <-j.release:
+	case <-ctx.Done():
+		j.cancelled <- ctx.Err()
+	}
+	return nil
+}
+
+// overlap adds a slow job which runs every minute, and lets it become due n times.
+func overlap(t *testing.T, n int, policy OverlapPolicy) (*Scheduler, *FakeClock, *slowJob) {
+	s, clock := startScheduler(t)
+	job := newSlowJob()
+	t.Cleanup(func() {
+		close(job.release)
+	})
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), job.run, WithOverlapPolicy(policy)))
+
+	for i := 0; i < n; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	clock.BlockUntil(1)
+	return s, clock, job
+}
+
+func TestOverlapAllow(t *testing.T) {
+	s, _, job := overlap(t, 2, OverlapAllow)
+	<-job.started
+	<-job.started
+
+	stats, ok := s.Stats("job")
+	require.True(t, ok)
+	assert.Equal(t, JobStats{Running: 2, Runs: 2}, stats)
+}
+
+func TestOverlapForbid(t *testing.T) {
+	s, clock, job := overlap(t, 3, OverlapForbid)
+	<-job.started
+	assert.Empty(t, job.started)
+
+	stats, _ := s.Stats("job")
+	assert.Equal(t, JobStats{Running: 1, Runs: 1, Skipped: 2}, stats)
+
+	// Once the run finishes the job runs again when it's next due
+	job.release <- struct{}{}
+	waitIdle(t, s, "job")
+	clock.Advance(time.Minute)
+	<-job.started
+}
+
+func TestOverlapQueue(t *testing.T) {
+	s, _, job := overlap(t, 3, OverlapQueue)
+	<-job.started
+	assert.Empty(t, job.started)
+
+	stats, _ := s.Stats("job")
+	assert.Equal(t, JobStats{Running: 1, Runs: 1, Skipped: 1, Queued: 1}, stats)
+
+	// The queued run starts as soon as the first finishes
+	job.release <- struct{}{}
+	<-job.started
+	stats, _ = s.Stats("job")
+	assert.Equal(t, JobStats{Running: 1, Runs: 2, Skipped: 1, Queued: 1}, stats)
+}
+
+func TestOverlapQueueRemoved(t *testing.T) {
+	s, _, job := overlap(t, 2, OverlapQueue)
+	<-job.started
+	assert.True(t, s.Remove("job"))
+
+	job.release <- struct{}{}
+	assert.Empty(t, job.started)
+}
+
+func TestOverlapReplace(t *testing.T) {
+	s, _, job := overlap(t, 2, OverlapReplace)
+	// The runs can start in either order, but only the first is cancelled.
+	runs := []context.Context{<-job.started, <-job.started}
+	assert.Equal(t, context.Canceled, <-job.cancelled)
+	assert.NotEqual(t, runs[0].Err() == nil, runs[1].Err() == nil)
+
+	stats, _ := s.Stats("job")
+	assert.Equal(t, uint64(2), stats.Runs)
+	assert.Equal(t, uint64(1), stats.Replaced)
+}
+
+func TestStatsMissingJob(t *testing.T) {
+	_, ok := NewScheduler().Stats("missing")
+	assert.False(t, ok)
+}
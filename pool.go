@@ -0,0 +1,188 @@
+package tokei
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// WithWorkers limits the scheduler to running n jobs at once. Runs which are due while every worker is busy
+// wait for one in order of priority and then of when they were due. By default there is no limit.
+func WithWorkers(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.pool.workers = n
+	}
+}
+
+// WithPriority sets the job's priority when waiting for a worker. Jobs with a higher priority run first.
+// It defaults to 0.
+func WithPriority(priority int) JobOption {
+	return func(j *job) {
+		j.priority = priority
+	}
+}
+
+// PoolStats describes a Scheduler's workers and the runs waiting for them.
+type PoolStats struct {
+	// Workers is the number of workers, or 0 if there is no limit.
+	Workers int
+	// Busy is the number of workers running a job.
+	Busy int
+	// Waiting is the number of runs waiting for a worker.
+	Waiting int
+	// Started is the number of runs which have started.
+	Started uint64
+	// TotalWait is the total time runs waited for a worker before starting, and MaxWait is the longest.
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// PoolStats returns the current stats for the scheduler's workers.
+func (s *Scheduler) PoolStats() PoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.pool.stats
+	stats.Workers = s.pool.workers
+	stats.Busy = s.pool.busy
+	stats.Waiting = len(s.pool.waiting)
+	return stats
+}
+
+// pool tracks the runs waiting for a worker. Its fields are guarded by the scheduler's mutex.
+type pool struct {
+	workers int
+	busy    int
+	waiting runQueue
+	seq     uint64
+	stats   PoolStats
+}
+
+// free reports whether a worker is available.
+func (p *pool) free() bool {
+	return p.workers <= 0 || p.busy < p.workers
+}
+
+// run is a single run of a job.
+type run struct {
+	job       *job
+	ctx       context.Context
+	cancel    context.CancelFunc
+	scheduled time.Time
+	submitted time.Time
+
+	// seq orders runs which are otherwise equal by when they were submitted.
+	seq uint64
+}
+
+// submit queues a run of a job to wait for a worker. The scheduler must call fill to start it.
+func (s *Scheduler) submit(j *job, scheduled time.Time) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	j.running++
+	j.cancel = cancel
+	s.pool.seq++
+	heap.Push(&s.pool.waiting, &run{
+		job:       j,
+		ctx:       ctx,
+		cancel:    cancel,
+		scheduled: scheduled,
+		submitted: s.clock.Now(),
+		seq:       s.pool.seq,
+	})
+}
+
+// fill starts waiting runs while there are workers available. Runs which were cancelled or whose job was removed
+// while waiting are dropped.
+func (s *Scheduler) fill() {
+	for len(s.pool.waiting) > 0 && s.pool.free() && !s.stopped {
+		r := heap.Pop(&s.pool.waiting).(*run)
+		if r.ctx.Err() != nil || s.jobs[r.job.id] != r.job {
+			reason := SkipCancelled
+			if s.jobs[r.job.id] != r.job {
+				reason = SkipRemoved
+			}
+			s.record(skipEvent, r.job, r.scheduled, reason)
+			r.cancel()
+			s.release(r.job)
+			continue
+		}
+		s.start(r)
+	}
+}
+
+// start runs a job in its own goroutine.
+func (s *Scheduler) start(r *run) {
+	wait := s.clock.Now().Sub(r.submitted)
+	s.pool.busy++
+	s.pool.stats.Started++
+	s.pool.stats.TotalWait += wait
+	if wait > s.pool.stats.MaxWait {
+		s.pool.stats.MaxWait = wait
+	}
+	r.job.stats.WaitTime += wait
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		// Pass on the events from before the run started, so that listeners see them before it starts.
+		s.flush()
+		if !s.lock(r) {
+			r.cancel()
+			s.finish(r, nil)
+			return
+		}
+		err := s.execute(r)
+		r.cancel()
+		if err != nil && s.onErr != nil {
+			s.onErr(r.job.id, err)
+		}
+		s.finish(r, err)
+	}()
+}
+
+// finish frees the worker which ran r and starts whatever is waiting for it.
+func (s *Scheduler) finish(r *run, err error) {
+	s.mu.Lock()
+	if err != nil {
+		r.job.stats.Failed++
+	}
+	s.pool.busy--
+	s.release(r.job)
+	s.fill()
+	s.mu.Unlock()
+	s.flush()
+}
+
+// runQueue is a heap of runs waiting for a worker, ordered by priority and then by when they were due.
+type runQueue []*run
+
+func (q runQueue) Len() int {
+	return len(q)
+}
+
+func (q runQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	switch {
+	case a.job.priority != b.job.priority:
+		return a.job.priority > b.job.priority
+	case !a.scheduled.Equal(b.scheduled):
+		return a.scheduled.Before(b.scheduled)
+	default:
+		return a.seq < b.seq
+	}
+}
+
+func (q runQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *runQueue) Push(x interface{}) {
+	*q = append(*q, x.(*run))
+}
+
+func (q *runQueue) Pop() interface{} {
+	old := *q
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return r
+}
@@ -0,0 +1,101 @@
+package tokei
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkers(t *testing.T) {
+	s, clock := startScheduler(t, WithWorkers(2))
+	job := newSlowJob()
+	defer close(job.release)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, s.AddJob(id, mustScheduleClock(t, "0 * * * *", clock), job.run))
+	}
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	<-job.started
+	<-job.started
+	assert.Equal(t, PoolStats{Workers: 2, Busy: 2, Waiting: 2, Started: 2}, s.PoolStats())
+
+	// Each run which finishes lets one which is waiting start
+	job.release <- struct{}{}
+	<-job.started
+	assert.Empty(t, job.started)
+	assert.Equal(t, PoolStats{Workers: 2, Busy: 2, Waiting: 1, Started: 3}, s.PoolStats())
+}
+
+func TestPriority(t *testing.T) {
+	s, clock := startScheduler(t, WithWorkers(1))
+	low, high := newSlowJob(), newSlowJob()
+	defer close(low.release)
+	defer close(high.release)
+	require.NoError(t, s.AddJob("low", mustScheduleClock(t, "0 * * * *", clock), low.run))
+	require.NoError(t, s.AddJob("high", mustScheduleClock(t, "0 * * * *", clock), high.run, WithPriority(10)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	<-high.started
+	assert.Empty(t, low.started)
+
+	// The low priority job waits until the high priority one finishes
+	clock.BlockUntil(1)
+	clock.Advance(time.Second * 30)
+	high.release <- struct{}{}
+	<-low.started
+
+	stats := s.PoolStats()
+	assert.Equal(t, uint64(2), stats.Started)
+	assert.Equal(t, time.Second*30, stats.TotalWait)
+	assert.Equal(t, time.Second*30, stats.MaxWait)
+
+	jobStats, _ := s.Stats("low")
+	assert.Equal(t, time.Second*30, jobStats.WaitTime)
+}
+
+func TestWorkersRemoved(t *testing.T) {
+	s, clock := startScheduler(t, WithWorkers(1))
+	first, second := newSlowJob(), newSlowJob()
+	defer close(first.release)
+	require.NoError(t, s.AddJob("first", mustScheduleClock(t, "0 * * * *", clock), first.run, WithPriority(1)))
+	require.NoError(t, s.AddJob("second", mustScheduleClock(t, "0 * * * *", clock), second.run))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	<-first.started
+
+	// Runs waiting for a worker are dropped when their job is removed
+	s.Remove("second")
+	first.release <- struct{}{}
+	assert.Eventually(t, func() bool {
+		return s.PoolStats().Busy == 0
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, second.started)
+	assert.Equal(t, 0, s.PoolStats().Waiting)
+}
+
+func TestRunQueue(t *testing.T) {
+	low := &job{}
+	high := &job{priority: 1}
+	runs := []*run{
+		{job: low, scheduled: epoch.Add(time.Minute), seq: 1},
+		{job: low, scheduled: epoch, seq: 3},
+		{job: low, scheduled: epoch, seq: 2},
+		{job: high, scheduled: epoch.Add(time.Hour), seq: 4},
+	}
+	var q runQueue
+	for _, r := range runs {
+		heap.Push(&q, r)
+	}
+
+	var order []uint64
+	for q.Len() > 0 {
+		order = append(order, heap.Pop(&q).(*run).seq)
+	}
+	assert.Equal(t, []uint64{4, 2, 3, 1}, order)
+}
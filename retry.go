@@ -0,0 +1,140 @@
+package tokei
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// RetryPolicy decides how a Scheduler retries a job which returns an error.
+// Retries hold on to the job's worker while they wait, and are given up if they wouldn't start before
+// the job is next due, so that a failing job doesn't run into its next scheduled time.
+type RetryPolicy struct {
+	// Attempts is the most times the job is run for each scheduled time, including the first.
+	// Values less than 2 don't retry.
+	Attempts int `json:"attempts,omitempty"`
+	// Backoff is how long to wait before the first retry.
+	Backoff time.Duration `json:"backoff,omitempty"`
+	// Multiplier is multiplied with the wait before each retry after the first. It defaults to 2.
+	Multiplier float64 `json:"multiplier,omitempty"`
+	// MaxBackoff limits the wait before any retry, if it is positive.
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty"`
+}
+
+// backoff returns how long to wait after the given attempt fails.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	backoff := float64(p.Backoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	if backoff > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(backoff)
+}
+
+// WithRetry retries the job according to policy when it returns an error.
+func WithRetry(policy RetryPolicy) JobOption {
+	return func(j *job) {
+		j.retry = policy
+	}
+}
+
+// WithTimeout cancels each attempt at the job if it runs for longer than d. The deadline is set on the
+// job's context, so is measured by the system clock even if the scheduler has a different one.
+func WithTimeout(d time.Duration) JobOption {
+	return func(j *job) {
+		j.timeout = d
+	}
+}
+
+// execute runs a job, retrying it while its retry policy allows, and returns the error from the last attempt.
+func (s *Scheduler) execute(r *run) error {
+	next := r.job.schedule.NextFrom(after(r.scheduled))
+	for attempt := 1; ; attempt++ {
+		err := s.attempt(r, attempt)
+		if err == nil || attempt >= r.job.retry.Attempts || r.ctx.Err() != nil {
+			return err
+		}
+		backoff := r.job.retry.backoff(attempt)
+		if !next.IsZero() && !s.clock.Now().Add(backoff).Before(next) {
+			return err
+		}
+		if !s.wait(r.ctx, backoff) {
+			return err
+		}
+		s.mu.Lock()
+		r.job.stats.Retries++
+		s.mu.Unlock()
+	}
+}
+
+// attempt runs a job once, with its timeout, details of the run and its trace in the context.
+func (s *Scheduler) attempt(r *run, attempt int) error {
+	ctx := context.WithValue(r.ctx, runKey{}, runInfo{
+		id:        r.job.id,
+		scheduled: r.scheduled,
+		attempt:   attempt,
+	})
+	if r.job.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.job.timeout)
+		defer cancel()
+	}
+	start := s.clock.Now()
+	s.emit(startEvent, r, Event{Time: start, Attempt: attempt})
+	ctx, endSpan := s.trace(ctx, r, attempt, start)
+	err := runTraced(ctx, r.job.run, endSpan)
+	end := s.clock.Now()
+	if err != nil {
+		s.emit(errorEvent, r, Event{Time: end, Attempt: attempt, Duration: end.Sub(start), Err: err})
+	} else {
+		s.emit(successEvent, r, Event{Time: end, Attempt: attempt, Duration: end.Sub(start)})
+	}
+	return err
+}
+
+// wait waits on the scheduler's clock for d to pass, returning false if ctx is done first.
+func (s *Scheduler) wait(ctx context.Context, d time.Duration) bool {
+	timer := s.clock.NewTimer(d)
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		timer.Stop()
+		return false
+	}
+}
+
+// runKey is the context key for the runInfo of a job's run.
+type runKey struct{}
+
+// runInfo describes the run of a job which a context belongs to.
+type runInfo struct {
+	id        string
+	scheduled time.Time
+	attempt   int
+}
+
+// JobID returns the ID of the job a Scheduler is running with ctx, or "" if ctx doesn't belong to a job.
+func JobID(ctx context.Context) string {
+	info, _ := ctx.Value(runKey{}).(runInfo)
+	return info.id
+}
+
+// ScheduledTime returns the time the job being run with ctx was scheduled for, which is earlier than now if the job
+// waited for a worker or is being retried. It returns the zero time if ctx doesn't belong to a job.
+func ScheduledTime(ctx context.Context) time.Time {
+	info, _ := ctx.Value(runKey{}).(runInfo)
+	return info.scheduled
+}
+
+// Attempt returns which attempt at running the job ctx belongs to, starting from 1, or 0 if ctx doesn't belong to a job.
+func Attempt(ctx context.Context) int {
+	info, _ := ctx.Value(runKey{}).(runInfo)
+	return info.attempt
+}
@@ -0,0 +1,152 @@
+package tokei
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		{"first", RetryPolicy{Backoff: time.Second}, 1, time.Second},
+		{"doubles", RetryPolicy{Backoff: time.Second}, 3, time.Second * 4},
+		{"multiplier", RetryPolicy{Backoff: time.Second, Multiplier: 3}, 3, time.Second * 9},
+		{"max", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 5}, 4, time.Second * 5},
+		{"overflow", RetryPolicy{Backoff: time.Hour}, 100, math.MaxInt64},
+		{"none", RetryPolicy{}, 2, 0},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.policy.backoff(test.attempt))
+		})
+	}
+}
+
+// attempts is a job which fails until its nth attempt, and sends the context of each attempt on runs.
+func attempts(n int, runs chan<- context.Context) JobFunc {
+	return func(ctx context.Context) error {
+		runs <- ctx
+		if Attempt(ctx) < n {
+			return errors.New("failed")
+		}
+		return nil
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s, clock := startScheduler(t)
+	runs := make(chan context.Context, 10)
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Second * 10}
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), attempts(3, runs), WithRetry(policy)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	for attempt := 1; attempt <= 3; attempt++ {
+		ctx := <-runs
+		assert.Equal(t, attempt, Attempt(ctx))
+		assert.Equal(t, epoch.Add(time.Hour), ScheduledTime(ctx))
+		assert.Equal(t, "job", JobID(ctx))
+
+		// Wait for the backoff, which doubles each time
+		if attempt < 3 {
+			clock.BlockUntil(2)
+			clock.Advance(policy.backoff(attempt))
+		}
+	}
+
+	waitIdle(t, s, "job")
+	stats, _ := s.Stats("job")
+	assert.Equal(t, uint64(2), stats.Retries)
+	assert.Zero(t, stats.Failed)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	failed := make(chan error, 1)
+	s, clock := startScheduler(t, WithErrorHandler(func(id string, err error) {
+		failed <- err
+	}))
+	runs := make(chan context.Context, 10)
+	policy := RetryPolicy{Attempts: 2}
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), attempts(3, runs), WithRetry(policy)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	assert.EqualError(t, <-failed, "failed")
+	assert.Len(t, runs, 2)
+
+	waitIdle(t, s, "job")
+	stats, _ := s.Stats("job")
+	assert.Equal(t, uint64(1), stats.Retries)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+func TestRetryBeforeNextTime(t *testing.T) {
+	failed := make(chan error, 1)
+	s, clock := startScheduler(t, WithErrorHandler(func(id string, err error) {
+		failed <- err
+	}))
+	runs := make(chan context.Context, 10)
+
+	// Retrying after an hour would run into the next scheduled time
+	policy := RetryPolicy{Attempts: 2, Backoff: time.Hour}
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), attempts(2, runs), WithRetry(policy)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	assert.EqualError(t, <-failed, "failed")
+	assert.Len(t, runs, 1)
+}
+
+func TestRetryStopped(t *testing.T) {
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	s := NewScheduler(WithSchedulerClock(clock))
+	go s.Start()
+	runs := make(chan context.Context, 10)
+	policy := RetryPolicy{Attempts: 2, Backoff: time.Minute}
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), attempts(2, runs), WithRetry(policy)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	<-runs
+
+	// Stopping the scheduler cancels the wait for a retry
+	clock.BlockUntil(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, s.Stop(ctx))
+	assert.NoError(t, s.Stop(context.Background()))
+	assert.Empty(t, runs)
+}
+
+func TestTimeout(t *testing.T) {
+	failed := make(chan error, 1)
+	s, clock := startScheduler(t, WithErrorHandler(func(id string, err error) {
+		failed <- err
+	}))
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(time.Millisecond)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	assert.Equal(t, context.DeadlineExceeded, <-failed)
+}
+
+func TestRunContextOutsideJob(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", JobID(ctx))
+	assert.True(t, ScheduledTime(ctx).IsZero())
+	assert.Equal(t, 0, Attempt(ctx))
+}
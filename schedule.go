@@ -7,97 +7,314 @@ import (
 
 // Schedule represents the schedule on which the job will fire for a given timezone.
 type Schedule struct {
-	location *time.Location
+	location   *time.Location
+	expression *CronExpression
 
 	// Cache these ranges on creation to avoid allocations in Next()
 	month, dayOfMonth, dayOfWeek, hours, minutes []int
+
+	calendar Calendar
+	shift    bool
+
+	notBefore, notAfter time.Time
+	limit               *occurrenceLimit
+
+	clock Clock
+}
+
+// occurrenceLimit caps the number of times a schedule matches.
+type occurrenceLimit struct {
+	count  int
+	anchor time.Time
+}
+
+// ScheduleOption configures optional behaviour of a Schedule.
+type ScheduleOption func(*Schedule)
+
+// WithClock sets the clock the schedule uses to tell the current time. It defaults to SystemClock.
+func WithClock(clock Clock) ScheduleOption {
+	return func(s *Schedule) {
+		s.clock = clock
+	}
+}
+
+// NotBefore stops the schedule matching any times before t.
+func NotBefore(t time.Time) ScheduleOption {
+	return func(s *Schedule) {
+		s.notBefore = t
+	}
+}
+
+// NotAfter stops the schedule matching any times after t, after which it is exhausted.
+func NotAfter(t time.Time) ScheduleOption {
+	return func(s *Schedule) {
+		s.notAfter = t
+	}
+}
+
+// MaxOccurrences stops the schedule after it has matched n times, counting from anchor.
+// Times before anchor are not matched, so anchor is usually when the job was created.
+func MaxOccurrences(n int, anchor time.Time) ScheduleOption {
+	return func(s *Schedule) {
+		s.limit = &occurrenceLimit{count: n, anchor: anchor}
+	}
 }
 
 // NewSchedule creates a new schedule for an expression in the given timezone.
-func NewSchedule(location *time.Location, ex *CronExpression) *Schedule {
-	return &Schedule{
+func NewSchedule(location *time.Location, ex *CronExpression, opts ...ScheduleOption) *Schedule {
+	s := &Schedule{
 		location:   location,
+		expression: ex,
 		month:      ex.month.Enumerate(),
 		dayOfMonth: ex.dayOfMonth.Enumerate(),
 		dayOfWeek:  ex.dayOfWeek.Enumerate(),
 		hours:      ex.hours.Enumerate(),
 		minutes:    ex.minutes.Enumerate(),
+		clock:      SystemClock,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.limit != nil {
+		s.applyLimit()
+	}
+	return s
+}
+
+// applyLimit converts the occurrence limit into bounds, so that the rest of the schedule only has to check those.
+// It is applied once all other options are set, since the calendar and bounds affect which times are counted.
+func (s *Schedule) applyLimit() {
+	if s.notBefore.IsZero() || s.notBefore.Before(s.limit.anchor) {
+		s.notBefore = s.limit.anchor
+	}
+	if s.limit.count <= 0 {
+		s.notAfter = s.notBefore.Add(-time.Nanosecond)
+		return
+	}
+	times := s.ProjectFrom(s.notBefore, s.limit.count)
+	if len(times) < s.limit.count {
+		return
+	}
+	if last := times[len(times)-1]; s.notAfter.IsZero() || last.Before(s.notAfter) {
+		s.notAfter = last
 	}
 }
 
 // NewScheduleUTC creates a new schedule for the expression in UTC.
-func NewScheduleUTC(ex *CronExpression) *Schedule {
-	return NewSchedule(time.UTC, ex)
+func NewScheduleUTC(ex *CronExpression, opts ...ScheduleOption) *Schedule {
+	return NewSchedule(time.UTC, ex, opts...)
+}
+
+// Expression returns the expression the schedule was created from.
+func (s *Schedule) Expression() *CronExpression {
+	return s.expression
+}
+
+// Location returns the timezone the schedule runs in.
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// ScheduleKey identifies a schedule by the canonical form of its expression, its location name and its bounds,
+// which include any limit on its occurrences. Schedules which fire at the same times have the same key, so it can
+// be used to deduplicate them in a map. Calendars can't be compared, so schedules with one are never deduplicated:
+// each has a key of its own.
+type ScheduleKey struct {
+	Expression string
+	Location   string
+	NotBefore  time.Time
+	NotAfter   time.Time
+
+	// calendar is the schedule itself if it has a calendar.
+	calendar *Schedule
+}
+
+// Key returns the key for the schedule.
+func (s *Schedule) Key() ScheduleKey {
+	key := ScheduleKey{
+		Expression: s.expression.String(),
+		Location:   s.location.String(),
+		NotBefore:  s.notBefore.UTC().Round(0),
+		NotAfter:   s.notAfter.UTC().Round(0),
+	}
+	if s.calendar != nil {
+		key.calendar = s
+	}
+	return key
+}
+
+// Equal reports whether two schedules have equal expressions in the same location with the same bounds.
+// Schedules with a calendar are only equal to themselves.
+func (s *Schedule) Equal(other *Schedule) bool {
+	if s.calendar != nil || other.calendar != nil {
+		return s == other
+	}
+	return s.location.String() == other.location.String() && Equal(s.expression, other.expression) &&
+		s.notBefore.Equal(other.notBefore) && s.notAfter.Equal(other.notAfter)
+}
+
+// now returns the current time from the schedule's clock.
+func (s *Schedule) now() time.Time {
+	return s.clock.Now()
 }
 
 // Timer returns a ScheduleTimer which fires on this schedule.
-func (s *Schedule) Timer() *ScheduleTimer {
-	return NewScheduleTimer(s)
+func (s *Schedule) Timer(opts ...TimerOption) *ScheduleTimer {
+	return NewScheduleTimer(s, opts...)
+}
+
+// Exhausted reports whether the schedule will never match again.
+func (s *Schedule) Exhausted() bool {
+	return s.Next().IsZero()
 }
 
-// Next returns the next time that matches the schedule.
+// Next returns the next time that matches the schedule, or the zero time if nothing does.
 func (s *Schedule) Next() time.Time {
-	return s.Project(1)[0]
+	return s.NextFrom(s.now())
 }
 
-// NextFrom returns the next time >= t which matches the schedule.
+// NextFrom returns the next time >= t which matches the schedule, or the zero time if nothing does.
 func (s *Schedule) NextFrom(t time.Time) time.Time {
-	return s.ProjectFrom(t, 1)[0]
+	return s.nextFrom(t.In(s.location), true)
+}
+
+// PrevFrom returns the latest time <= t which matches the schedule, or the zero time if nothing does.
+// It searches forwards through windows which double in size going back from t, so it takes all options into account.
+func (s *Schedule) PrevFrom(t time.Time) time.Time {
+	t = t.In(s.location)
+	limit := t.AddDate(-searchYears, 0, 0)
+	for span := time.Minute; ; span *= 2 {
+		from := t.Add(-span)
+		var prev time.Time
+		for next := s.nextFrom(from, true); !next.IsZero() && !next.After(t); next = s.nextFrom(next, false) {
+			prev = next
+		}
+		if !prev.IsZero() {
+			return prev
+		}
+		if from.Before(limit) {
+			return time.Time{}
+		}
+	}
 }
 
 // Project returns the next N times that the expression is matched.
 func (s *Schedule) Project(n int) []time.Time {
-	return s.ProjectFrom(time.Now(), n)
+	return s.ProjectFrom(s.now(), n)
 }
 
 // ProjectFrom returns the next N matching times after t. If t matches the expression,
-// it is counted in the results.
+// it is counted in the results. Fewer than N times are returned if the schedule stops matching.
 func (s *Schedule) ProjectFrom(t time.Time, n int) []time.Time {
 	last := t.In(s.location)
-	results := make([]time.Time, n)
+	results := make([]time.Time, 0, n)
 	for i := 0; i < n; i++ {
-		next := s.calculateNextFromTime(last, i == 0)
-		results[i] = next
+		next := s.nextFrom(last, i == 0)
+		if next.IsZero() {
+			break
+		}
+		results = append(results, next)
 		last = next
 	}
 	return results
 }
 
-func (s *Schedule) calculateNextFromTime(t time.Time, matchSame bool) time.Time {
-	getNext := func(current time.Time) time.Time {
-		// Starting at t, find the next month in which t matches the schedule
-		for !s.matchesMonth(current.Month()) {
-			current = current.AddDate(0, 1, 0)
-			// NOTE: Add similar logic to below if we want to match specific years.
+// runEnd returns the first whole minute after t, which it matches, that the schedule doesn't match,
+// or limit if it matches every minute until then.
+func (s *Schedule) runEnd(t, limit time.Time) time.Time {
+	current := t.In(s.location)
+	for current.Before(limit) {
+		if !s.nextFrom(current, true).Equal(current) {
+			return current
 		}
+		current = s.step(current)
+	}
+	return limit
+}
 
-		for !s.matchesDayOfMonth(current.Day()) || !s.matchesDayOfWeek(current.Weekday()) {
-			current = current.AddDate(0, 0, 1)
-
-			// Wrapped around to the 1st which increments the month, so now we need to start from the top
-			// of that month again.
-			if current.Day() == 1 {
-				reset := time.Date(current.Year(), current.Month(), current.Day(), 0, 0, 0, 0, t.Location())
-				return s.calculateNextFromTime(reset, true)
-			}
-		}
+// step moves on from t, which the schedule matches, to the start of the next minute, hour, day or month.
+// It takes the largest step which the schedule is sure to match all of.
+func (s *Schedule) step(t time.Time) time.Time {
+	year, month, day := t.Date()
+	var next time.Time
+	switch {
+	case s.limit != nil || len(s.minutes) < 60:
+		next = t.Add(time.Minute)
+	case len(s.hours) < 24:
+		next = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, s.location)
+	case s.calendar != nil || len(s.dayOfMonth) < 31 || len(s.dayOfWeek) < 7:
+		next = time.Date(year, month, day+1, 0, 0, 0, 0, s.location)
+	default:
+		next = time.Date(year, month+1, 1, 0, 0, 0, 0, s.location)
+	}
+	if !s.notAfter.IsZero() && next.After(s.notAfter) {
+		return s.notAfter.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	}
+	return next
+}
 
-		for !s.matchesHour(current.Hour()) {
-			current = current.Add(time.Hour)
-		}
+// nextFrom finds the next matching time within the schedule's bounds.
+func (s *Schedule) nextFrom(t time.Time, matchSame bool) time.Time {
+	if !s.notAfter.IsZero() && t.After(s.notAfter) {
+		return time.Time{}
+	}
+	if !s.notBefore.IsZero() && t.Before(s.notBefore) {
+		t, matchSame = s.notBefore.In(s.location), true
+	}
+	next := s.nextIncludedFrom(t, matchSame)
+	if !s.notAfter.IsZero() && next.After(s.notAfter) {
+		return time.Time{}
+	}
+	return next
+}
 
-		for !s.matchesMinute(current.Minute()) {
-			current = current.Add(time.Minute)
-		}
-		return current
+// nextIncludedFrom finds the next matching time, taking the calendar into account.
+func (s *Schedule) nextIncludedFrom(t time.Time, matchSame bool) time.Time {
+	switch {
+	case s.calendar == nil:
+		return s.calculateNextFromTime(t, matchSame)
+	case s.shift:
+		return s.shiftedNextFromTime(t, matchSame)
+	default:
+		return s.includedNextFromTime(t, matchSame)
 	}
+}
 
+func (s *Schedule) calculateNextFromTime(t time.Time, matchSame bool) time.Time {
+	// Schedules only match whole minutes, so start from the first whole minute at or after t.
 	// If we don't want to match the current time (maybe because we want to generate the next N times from now)
 	// add a minute to move us along.
-	if matchSame {
-		return getNext(t)
+	current := t.Truncate(time.Minute)
+	if current.Before(t) || !matchSame {
+		current = current.Add(time.Minute)
+	}
+
+	// Whenever a part doesn't match, move to the start of the next month, day or hour so that
+	// the smaller parts are searched from their beginning. Some expressions never match, such as the 30th of
+	// February, so give up after searchYears.
+	location := current.Location()
+	limit := t.AddDate(searchYears, 0, 0)
+	for current.Before(limit) {
+		year, month, day := current.Date()
+		if !s.matchesMonth(month) {
+			current = time.Date(year, month+1, 1, 0, 0, 0, 0, location)
+			continue
+		}
+		if !s.matchesDayOfMonth(day) || !s.matchesDayOfWeek(current.Weekday()) {
+			current = time.Date(year, month, day+1, 0, 0, 0, 0, location)
+			continue
+		}
+		if !s.matchesHour(current.Hour()) {
+			current = time.Date(year, month, day, current.Hour()+1, 0, 0, 0, location)
+			continue
+		}
+		if !s.matchesMinute(current.Minute()) {
+			current = current.Add(time.Minute)
+			continue
+		}
+		return current
 	}
-	return getNext(t.Add(time.Minute))
+	return time.Time{}
 }
 
 func (s *Schedule) matchesMonth(month time.Month) bool {
@@ -124,39 +341,6 @@ func (s *Schedule) matchesMinute(minute int) bool {
 	return contains(s.minutes, minute)
 }
 
-// ScheduleTimer is a timer which runs on the cron schedule.
-type ScheduleTimer struct {
-	schedule  *Schedule
-	timeChan  chan time.Time
-	closeChan chan struct{}
-}
-
-// NewScheduleTimer creates a new timer.
-func NewScheduleTimer(schedule *Schedule) *ScheduleTimer {
-	return &ScheduleTimer{
-		schedule:  schedule,
-		timeChan:  make(chan time.Time),
-		closeChan: make(chan struct{}),
-	}
-}
-
-// Next returns a channel which upon which times will be sent when
-// the schedule matches the cron expression.
-// Timers must be started with Start().
-func (st *ScheduleTimer) Next() <-chan time.Time {
-	return st.timeChan
-}
-
-// Start starts the timer.
-func (st *ScheduleTimer) Start() {
-	for {
-		next := st.schedule.Next()
-		diff := next.Sub(time.Now().In(st.schedule.location))
-		time.Sleep(diff)
-		st.timeChan <- next
-	}
-}
-
 // contains makes use of the fact that all expression enumerations are inherently sorted
 // and uses a binary search to determine if there is a match.
 func contains(haystack []int, needle int) bool {
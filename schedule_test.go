@@ -40,6 +40,69 @@ func TestNext(t *testing.T) {
 		// At every 5th minute from 10 through 59 past every hour from 3 through 5 on day-of-month 1 and 2 and on Tuesday in July.
 		// First occurrence is Tuesday 2nd July 1974 03:10:00
 		{"complex", "10/5 3-5 1,2 7 2", epoch, epoch.AddDate(4, 6, 1).Add(time.Hour*3 + time.Minute*10)},
+
+		// February never has a 30th, so nothing matches.
+		{"impossible date", "0 0 30 2 *", epoch, time.Time{}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ex, err := Parse(test.input)
+			require.NoError(t, err)
+
+			sched := NewScheduleUTC(ex)
+			output := sched.NextFrom(test.startTime)
+			assert.Equal(t, test.expected, output)
+		})
+	}
+}
+
+func TestScheduleNeverMatches(t *testing.T) {
+	ex, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		schedule *Schedule
+	}{
+		{"plain", NewScheduleUTC(ex, WithClock(NewFakeClock(epoch)))},
+		{"calendar", NewScheduleUTC(ex, WithClock(NewFakeClock(epoch)), WithCalendar(NewDateSet()))},
+		{"calendar shift", NewScheduleUTC(ex, WithClock(NewFakeClock(epoch)), WithCalendarShift(NewDateSet()))},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.True(t, test.schedule.Exhausted())
+			assert.Empty(t, test.schedule.ProjectFrom(epoch, 3))
+			assert.True(t, test.schedule.PrevFrom(epoch).IsZero())
+			assert.True(t, Intersect(test.schedule, mustSchedule(t, "* * * * *")).NextFrom(epoch).IsZero())
+		})
+	}
+}
+
+// TestNextFromPartialTimes checks that the search starts from a whole minute, and that smaller parts of the time
+// start from their beginning when a larger part moves forwards.
+func TestNextFromPartialTimes(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		startTime time.Time
+		expected  time.Time
+	}{
+		// Start at 10:30 and ask for 07:00. The minutes should reset when moving to hour 7.
+		{"reset minutes", "0 7 * * *", epoch.Add(time.Hour*10 + time.Minute*30), epoch.AddDate(0, 0, 1).Add(time.Hour * 7)},
+
+		// Start at 10:30 and ask for midnight on the 5th. The hours should reset when moving to the 5th.
+		{"reset hours", "0 0 5 * *", epoch.Add(time.Hour*10 + time.Minute*30), epoch.AddDate(0, 0, 4)},
+
+		// Start at 10:30 on the 10th and ask for midnight in March. The day should reset when moving to March.
+		{"reset days", "0 0 * 3 *", epoch.AddDate(0, 0, 9).Add(time.Hour*10 + time.Minute*30), epoch.AddDate(0, 2, 0)},
+
+		// Start part way through a minute. The next whole minute is the first which can match.
+		{"round up seconds", "* * * * *", epoch.Add(time.Second * 30), epoch.Add(time.Minute)},
+
+		// Start part way through a matching minute of a later hour, which also rounds up.
+		{"round up in matching minute", "*/2 * * * *", epoch.Add(time.Hour*2 + time.Second*30), epoch.Add(time.Hour*2 + time.Minute*2)},
 	}
 
 	for _, test := range cases {
@@ -65,36 +128,161 @@ func TestNextMultiple(t *testing.T) {
 	assert.Equal(t, expected, next)
 }
 
-func TestTimer(t *testing.T) {
-	ex, err := Parse("* * * * *")
+func TestPrev(t *testing.T) {
+	cases := []struct {
+		name      string
+		input     string
+		startTime time.Time
+		expected  time.Time
+	}{
+		{"every minute", "* * * * *", epoch, epoch},
+		{"round down seconds", "* * * * *", epoch.Add(time.Second * 30), epoch},
+		{"minute 5", "5 * * * *", epoch, epoch.Add(-time.Minute * 55)},
+		{"daily", "0 9 * * *", epoch.Add(time.Hour * 8), epoch.Add(-time.Hour * 15)},
+		{"leap day", "0 0 29 2 *", epoch, time.Date(1968, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"complex", "10/5 3-5 1,2 7 2", epoch.AddDate(4, 6, 2), epoch.AddDate(4, 6, 1).Add(time.Hour*5 + time.Minute*55)},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ex, err := Parse(test.input)
+			require.NoError(t, err)
+
+			sched := NewScheduleUTC(ex)
+			assert.Equal(t, test.expected, sched.PrevFrom(test.startTime))
+		})
+	}
+}
+
+func TestPrevWithOptions(t *testing.T) {
+	ex, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+
+	sched := NewScheduleUTC(ex, WithCalendar(NewDateSet(epoch)))
+	assert.Equal(t, epoch.AddDate(0, 0, -1).Add(time.Hour*9), sched.PrevFrom(epoch.Add(time.Hour*12)))
+
+	sched = NewScheduleUTC(ex, NotBefore(epoch))
+	assert.True(t, sched.PrevFrom(epoch.Add(time.Hour*8)).IsZero())
+}
+
+func TestScheduleEqual(t *testing.T) {
+	a, err := Parse("0,15,30,45 * * * *")
+	require.NoError(t, err)
+	b, err := Parse("0-59/15 * * * *")
+	require.NoError(t, err)
+	c, err := Parse("*/10 * * * *")
+	require.NoError(t, err)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
 	require.NoError(t, err)
-	schedule := NewScheduleUTC(ex)
 
-	startTime := time.Now()
-	timer := schedule.Timer()
-	go timer.Start()
+	assert.True(t, NewScheduleUTC(a).Equal(NewScheduleUTC(b)))
+	assert.False(t, NewScheduleUTC(a).Equal(NewScheduleUTC(c)))
+	assert.False(t, NewScheduleUTC(a).Equal(NewSchedule(berlin, b)))
 
-	// Should immediately receive as current time is matched
-	out := <-timer.Next()
-	assert.WithinDuration(t, startTime, out, time.Minute)
+	jobs := map[ScheduleKey]string{}
+	jobs[NewScheduleUTC(a).Key()] = "a"
+	jobs[NewScheduleUTC(b).Key()] = "b"
+	jobs[NewSchedule(berlin, a).Key()] = "berlin"
+	assert.Equal(t, map[ScheduleKey]string{
+		{Expression: "*/15 * * * *", Location: "UTC"}:           "b",
+		{Expression: "*/15 * * * *", Location: "Europe/Berlin"}: "berlin",
+	}, jobs)
 }
 
-func TestTimerLong(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-		return
+func TestScheduleEqualBounds(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+
+	// Bounds, including those from limiting the occurrences, are part of a schedule
+	plain := NewScheduleUTC(ex)
+	bounded := NewScheduleUTC(ex, NotAfter(epoch.Add(time.Hour*3)))
+	limited := NewScheduleUTC(ex, MaxOccurrences(3, epoch.Add(time.Minute)))
+	assert.False(t, plain.Equal(bounded))
+	assert.NotEqual(t, plain.Key(), bounded.Key())
+	assert.False(t, bounded.Equal(limited))
+
+	sameBounds := NewScheduleUTC(ex, NotBefore(epoch.Add(time.Minute)), NotAfter(epoch.Add(time.Hour*3)))
+	assert.True(t, limited.Equal(sameBounds))
+	assert.Equal(t, limited.Key(), sameBounds.Key())
+
+	// Schedules with calendars are only equal to themselves
+	holidays := NewDateSet(epoch)
+	withCalendar := NewScheduleUTC(ex, WithCalendar(holidays))
+	assert.True(t, withCalendar.Equal(withCalendar))
+	assert.Equal(t, withCalendar.Key(), withCalendar.Key())
+	assert.False(t, withCalendar.Equal(NewScheduleUTC(ex, WithCalendar(holidays))))
+	assert.True(t, withCalendar.Key() != NewScheduleUTC(ex, WithCalendar(holidays)).Key())
+	assert.False(t, plain.Equal(withCalendar))
+}
+
+func TestScheduleBounds(t *testing.T) {
+	ex, err := Parse("0 */2 * * *")
+	require.NoError(t, err)
+	start := epoch.AddDate(0, 0, 1).Add(time.Hour * 9)
+	end := epoch.AddDate(0, 0, 1).Add(time.Hour * 14)
+	sched := NewScheduleUTC(ex, NotBefore(start), NotAfter(end))
+
+	expected := []time.Time{
+		epoch.AddDate(0, 0, 1).Add(time.Hour * 10),
+		epoch.AddDate(0, 0, 1).Add(time.Hour * 12),
+		epoch.AddDate(0, 0, 1).Add(time.Hour * 14),
+	}
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 5))
+	assert.Equal(t, expected[1:], sched.ProjectFrom(expected[1], 5))
+	assert.True(t, sched.NextFrom(end.Add(time.Minute)).IsZero())
+	assert.True(t, sched.Exhausted())
+}
+
+func TestScheduleNotBeforeMatches(t *testing.T) {
+	ex, err := Parse("0 */2 * * *")
+	require.NoError(t, err)
+	start := epoch.Add(time.Hour * 4)
+	sched := NewScheduleUTC(ex, NotBefore(start))
+
+	assert.Equal(t, start, sched.NextFrom(epoch))
+	assert.False(t, sched.Exhausted())
+}
+
+func TestScheduleMaxOccurrences(t *testing.T) {
+	ex, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+	anchor := epoch.AddDate(0, 0, 1)
+	sched := NewScheduleUTC(ex, MaxOccurrences(3, anchor))
+
+	expected := []time.Time{
+		epoch.AddDate(0, 0, 1).Add(time.Hour * 9),
+		epoch.AddDate(0, 0, 2).Add(time.Hour * 9),
+		epoch.AddDate(0, 0, 3).Add(time.Hour * 9),
 	}
-	ex, err := Parse("*/2 * * * *")
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 10))
+	assert.Equal(t, expected[2:], sched.ProjectFrom(expected[2], 10))
+	assert.True(t, sched.Exhausted())
+}
+
+func TestScheduleMaxOccurrencesWithOptions(t *testing.T) {
+	ex, err := Parse("0 9 * * *")
 	require.NoError(t, err)
-	schedule := NewScheduleUTC(ex)
 
-	startTime := time.Now()
-	timer := schedule.Timer()
-	go timer.Start()
+	// Skipped days aren't counted, regardless of the order options are given in.
+	holidays := NewDateSet(epoch.AddDate(0, 0, 1))
+	sched := NewScheduleUTC(ex, MaxOccurrences(2, epoch), WithCalendar(holidays))
+	expected := []time.Time{
+		epoch.Add(time.Hour * 9),
+		epoch.AddDate(0, 0, 2).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, sched.ProjectFrom(epoch, 10))
+
+	// An earlier end still applies.
+	sched = NewScheduleUTC(ex, MaxOccurrences(2, epoch), NotAfter(epoch.Add(time.Hour*12)))
+	assert.Equal(t, expected[:1], sched.ProjectFrom(epoch, 10))
+
+	// As does a later start.
+	sched = NewScheduleUTC(ex, MaxOccurrences(2, epoch), NotBefore(epoch.AddDate(0, 0, 2)))
+	assert.Equal(t, []time.Time{expected[1], epoch.AddDate(0, 0, 3).Add(time.Hour * 9)}, sched.ProjectFrom(epoch, 10))
 
-	out := <-timer.Next()
-	// Should fire within the next minute (+ a second buffer.)
-	assert.WithinDuration(t, startTime, out, time.Minute+time.Second)
+	sched = NewScheduleUTC(ex, MaxOccurrences(0, epoch))
+	assert.Empty(t, sched.ProjectFrom(epoch, 10))
 }
 
 var benchCases = []struct {
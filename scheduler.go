@@ -0,0 +1,368 @@
+package tokei
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrJobExists is returned when adding a job with the same ID as one already in the scheduler.
+var ErrJobExists = errors.New("job already exists")
+
+// JobFunc is the work a Scheduler runs whenever a job's schedule matches.
+// The context is cancelled if the scheduler is stopped before the job finishes.
+type JobFunc func(ctx context.Context) error
+
+// Scheduler runs many jobs, each on its own schedule. Jobs are kept in a single queue ordered by when they
+// next fire, so a scheduler only needs one goroutine to wait on the clock however many jobs it has.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	queue   jobQueue
+	pool    pool
+	clock   Clock
+	store   Store
+	locker  Locker
+	lockTTL time.Duration
+	onErr   func(id string, err error)
+
+	// listeners are passed events, and events are those recorded while mu was held which are waiting to be passed on.
+	// emitMu is held while passing them on, so that they are seen in order.
+	listeners []Listener
+	events    []pendingEvent
+	emitMu    sync.Mutex
+	metrics   Metrics
+	tracer    Tracer
+
+	wake    chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+	stopped bool
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// SchedulerOption configures optional behaviour of a Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithSchedulerClock sets the clock the scheduler waits on. It defaults to SystemClock.
+func WithSchedulerClock(clock Clock) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clock = clock
+	}
+}
+
+// WithErrorHandler calls f with the ID of any job which returns an error, and the error it returned.
+// Jobs which are retried only call f if their last attempt fails.
+func WithErrorHandler(f func(id string, err error)) SchedulerOption {
+	return func(s *Scheduler) {
+		s.onErr = f
+	}
+}
+
+// NewScheduler creates a scheduler with no jobs. Jobs can be added before or after it is started.
+func NewScheduler(opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		jobs:   make(map[string]*job),
+		clock:  SystemClock,
+		tracer: NopTracer{},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// job is a job registered with a Scheduler. Its fields are guarded by the scheduler's mutex.
+type job struct {
+	id       string
+	schedule *Schedule
+	run      JobFunc
+	overlap  OverlapPolicy
+	priority int
+	timeout  time.Duration
+	retry    RetryPolicy
+	misfire  MisfirePolicy
+
+	// next is when the job next fires, and index is its position in the queue, or -1 if it isn't queued.
+	next  time.Time
+	index int
+
+	// running is the number of runs in progress or waiting for a worker, and cancel cancels the latest one.
+	// queued is the scheduled time of a run waiting for the others to finish, or zero if there isn't one.
+	running int
+	cancel  context.CancelFunc
+	queued  time.Time
+	stats   JobStats
+}
+
+// JobOption configures optional behaviour of a job added to a Scheduler.
+type JobOption func(*job)
+
+// AddJob registers run to be called whenever schedule matches, from now on, and saves the job to the store if
+// the scheduler has one. It returns ErrJobExists if there's already a job with the same ID.
+func (s *Scheduler) AddJob(id string, schedule *Schedule, run JobFunc, opts ...JobOption) error {
+	s.mu.Lock()
+	if _, ok := s.jobs[id]; ok {
+		s.mu.Unlock()
+		return ErrJobExists
+	}
+	j := &job{id: id, schedule: schedule, run: run, index: -1}
+	for _, opt := range opts {
+		opt(j)
+	}
+	next := schedule.NextFrom(s.clock.Now())
+	j.next = next
+
+	// The job holds its ID while it is saved, without being queued, so that the store isn't written with the
+	// scheduler locked.
+	s.jobs[id] = j
+	s.mu.Unlock()
+
+	if s.store != nil {
+		if err := s.store.Save(j.record()); err != nil {
+			s.mu.Lock()
+			if s.jobs[id] == j {
+				delete(s.jobs, id)
+			}
+			s.mu.Unlock()
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	current, taken := s.jobs[id]
+	if current == j {
+		s.enqueue(j, next)
+		s.notify()
+	}
+	s.mu.Unlock()
+
+	if !taken && s.store != nil {
+		// The job was removed while it was being saved, so it may have been deleted from the store before being saved.
+		if err := s.store.Delete(id); err != nil && s.onErr != nil {
+			s.onErr(id, err)
+		}
+	}
+	return nil
+}
+
+// Remove stops a job from being run again and deletes it from the store, reporting whether it was registered.
+// If the job is running it is allowed to finish, but runs waiting for a worker are dropped.
+func (s *Scheduler) Remove(id string) bool {
+	s.mu.Lock()
+	j, ok := s.jobs[id]
+	if ok {
+		delete(s.jobs, id)
+		if j.index >= 0 {
+			heap.Remove(&s.queue, j.index)
+		}
+		if s.metrics != nil {
+			s.metrics.SetNextFire(id, time.Time{})
+		}
+		s.notify()
+	}
+	s.mu.Unlock()
+
+	if ok && s.store != nil {
+		if err := s.store.Delete(id); err != nil && s.onErr != nil {
+			s.onErr(id, err)
+		}
+	}
+	return ok
+}
+
+// JobStats counts what a Scheduler has done with a job's runs.
+type JobStats struct {
+	// Running is the number of runs in progress or waiting for a worker.
+	Running int
+	// Runs is the number of runs started.
+	Runs uint64
+	// Skipped is the number of runs which weren't started because an earlier one was still going.
+	Skipped uint64
+	// Queued is the number of runs which waited for an earlier one to finish.
+	Queued uint64
+	// Replaced is the number of runs which were cancelled to start a new one.
+	Replaced uint64
+	// WaitTime is the total time runs waited for a worker before starting.
+	WaitTime time.Duration
+	// Retries is the number of times a failed run was tried again.
+	Retries uint64
+	// Failed is the number of runs which returned an error from their last attempt.
+	Failed uint64
+	// LockedOut is the number of runs which weren't started because another scheduler had locked them.
+	LockedOut uint64
+}
+
+// Stats returns the stats for a job, or false if there's no job with the ID.
+func (s *Scheduler) Stats(id string) (JobStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return JobStats{}, false
+	}
+	stats := j.stats
+	stats.Running = j.running
+	return stats, true
+}
+
+// Start runs jobs as they become due, returning once the scheduler is stopped. It should only be called once.
+func (s *Scheduler) Start() {
+	for {
+		wait, updates, ok := s.dispatch()
+		if !ok {
+			return
+		}
+		s.flush()
+		s.persist(updates)
+		var timeout <-chan time.Time
+		var timer ClockTimer
+		if wait >= 0 {
+			if wait > DefaultCheckInterval {
+				// Check the clock regularly in case it jumps or the machine is suspended, as ScheduleTimer does.
+				wait = DefaultCheckInterval
+			}
+			timer = s.clock.NewTimer(wait)
+			timeout = timer.C()
+		}
+		select {
+		case <-timeout:
+		case <-s.wake:
+		case <-s.stop:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// Stop stops the scheduler from running any more jobs and waits for running jobs to finish.
+// If ctx is done first, running jobs are cancelled and ctx's error is returned. It is safe to call more than once.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	defer s.cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch starts every job which is due, and returns how long to wait until the next one,
+// or a negative duration if there aren't any, along with the changes to save to the store.
+// It returns false once the scheduler has been stopped.
+func (s *Scheduler) dispatch() (time.Duration, []runUpdate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return 0, nil, false
+	}
+	var updates []runUpdate
+	now := s.clock.Now()
+	for len(s.queue) > 0 && !s.queue[0].next.After(now) {
+		j := heap.Pop(&s.queue).(*job)
+		scheduled := j.next
+		s.trigger(j, scheduled)
+
+		// Search strictly after the time which just fired, so it only fires once, and skip any which were
+		// missed because the scheduler was busy or suspended, telling listeners about them.
+		from := now
+		if !from.After(scheduled) {
+			from = after(scheduled)
+		}
+		next := j.schedule.NextFrom(from)
+		if len(s.listeners) > 0 {
+			// Times up to now are missed, apart from now itself if it is next.
+			until := now
+			if !next.IsZero() && !next.After(until) {
+				until = next.Add(-time.Nanosecond)
+			}
+			if missed := j.schedule.NextFrom(after(scheduled)); !missed.IsZero() && !missed.After(until) {
+				for _, t := range dueTimes(j.schedule, missed, until) {
+					s.record(misfireEvent, j, t, 0)
+				}
+			}
+		}
+		s.enqueue(j, next)
+		if s.store != nil {
+			updates = append(updates, runUpdate{id: j.id, last: scheduled, next: next})
+		}
+	}
+	s.fill()
+	if len(s.queue) == 0 {
+		return -1, updates, true
+	}
+	return s.queue[0].next.Sub(now), updates, true
+}
+
+// enqueue queues the job to fire at next, unless its schedule is exhausted.
+func (s *Scheduler) enqueue(j *job, next time.Time) {
+	if s.metrics != nil {
+		s.metrics.SetNextFire(j.id, next)
+	}
+	if next.IsZero() {
+		return
+	}
+	j.next = next
+	heap.Push(&s.queue, j)
+}
+
+// notify wakes the scheduler so that it sees changes to the queue.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// jobQueue is a min-heap of jobs ordered by when they next fire.
+type jobQueue []*job
+
+func (q jobQueue) Len() int {
+	return len(q)
+}
+
+func (q jobQueue) Less(i, j int) bool {
+	return q[i].next.Before(q[j].next)
+}
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *jobQueue) Push(x interface{}) {
+	j := x.(*job)
+	j.index = len(*q)
+	*q = append(*q, j)
+}
+
+func (q *jobQueue) Pop() interface{} {
+	old := *q
+	j := old[len(old)-1]
+	old[len(old)-1] = nil
+	j.index = -1
+	*q = old[:len(old)-1]
+	return j
+}
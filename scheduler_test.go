@@ -0,0 +1,205 @@
+package tokei
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustScheduleClock(t *testing.T, input string, clock Clock) *Schedule {
+	ex, err := Parse(input)
+	require.NoError(t, err)
+	return NewScheduleUTC(ex, WithClock(clock))
+}
+
+// startScheduler starts a scheduler on a fake clock set just after the epoch, and stops it when the test ends.
+func startScheduler(t *testing.T, opts ...SchedulerOption) (*Scheduler, *FakeClock) {
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	s := NewScheduler(append([]SchedulerOption{WithSchedulerClock(clock)}, opts...)...)
+	started := make(chan struct{})
+	go func() {
+		s.Start()
+		close(started)
+	}()
+	t.Cleanup(func() {
+		s.Stop(context.Background())
+		<-started
+	})
+	return s, clock
+}
+
+// waitIdle waits until none of the job's runs are running or waiting to run.
+func waitIdle(t *testing.T, s *Scheduler, id string) {
+	assert.Eventually(t, func() bool {
+		stats, _ := s.Stats(id)
+		return stats.Running == 0
+	}, time.Second, time.Millisecond)
+}
+
+// recordRuns returns a job which sends the time it ran on runs.
+func recordRuns(clock Clock, runs chan<- time.Time) JobFunc {
+	return func(context.Context) error {
+		runs <- clock.Now()
+		return nil
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s, clock := startScheduler(t)
+	minutes := make(chan time.Time, 10)
+	hours := make(chan time.Time, 10)
+	require.NoError(t, s.AddJob("minutes", mustScheduleClock(t, "* * * * *", clock), recordRuns(clock, minutes)))
+	require.NoError(t, s.AddJob("hours", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, hours)))
+
+	for i := 1; i <= 60; i++ {
+		clock.BlockUntil(1)
+		clock.Set(epoch.Add(time.Minute * time.Duration(i)))
+		assert.Equal(t, epoch.Add(time.Minute*time.Duration(i)), <-minutes)
+	}
+	assert.Equal(t, epoch.Add(time.Hour), <-hours)
+	assert.Empty(t, hours)
+}
+
+func TestSchedulerAddJob(t *testing.T) {
+	s, clock := startScheduler(t)
+	runs := make(chan time.Time, 10)
+	schedule := mustScheduleClock(t, "* * * * *", clock)
+
+	require.NoError(t, s.AddJob("job", schedule, recordRuns(clock, runs)))
+	assert.Equal(t, ErrJobExists, s.AddJob("job", schedule, recordRuns(clock, runs)))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute+time.Second*10), <-runs)
+}
+
+func TestSchedulerRemove(t *testing.T) {
+	s, clock := startScheduler(t)
+	removed := make(chan time.Time, 10)
+	kept := make(chan time.Time, 10)
+	require.NoError(t, s.AddJob("removed", mustScheduleClock(t, "* * * * *", clock), recordRuns(clock, removed)))
+	require.NoError(t, s.AddJob("kept", mustScheduleClock(t, "*/2 * * * *", clock), recordRuns(clock, kept)))
+
+	assert.True(t, s.Remove("removed"))
+	assert.False(t, s.Remove("removed"))
+	assert.False(t, s.Remove("missing"))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute * 2))
+	assert.Equal(t, epoch.Add(time.Minute*2), <-kept)
+	assert.Empty(t, removed)
+}
+
+func TestSchedulerExhausted(t *testing.T) {
+	s, clock := startScheduler(t)
+	runs := make(chan time.Time, 10)
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	schedule := NewScheduleUTC(ex, WithClock(clock), NotAfter(epoch.Add(time.Minute)))
+	require.NoError(t, s.AddJob("job", schedule, recordRuns(clock, runs)))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute))
+	assert.Equal(t, epoch.Add(time.Minute), <-runs)
+
+	// Nothing is left to wait for
+	require.NoError(t, s.AddJob("other", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, runs)))
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	assert.Equal(t, epoch.Add(time.Hour), <-runs)
+	assert.Empty(t, runs)
+}
+
+func TestSchedulerErrorHandler(t *testing.T) {
+	failed := make(chan string, 1)
+	s, clock := startScheduler(t, WithErrorHandler(func(id string, err error) {
+		assert.EqualError(t, err, "boom")
+		failed <- id
+	}))
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(context.Context) error {
+		return errors.New("boom")
+	}))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, "job", <-failed)
+}
+
+func TestSchedulerStopDrains(t *testing.T) {
+	s, clock := startScheduler(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- s.Stop(context.Background())
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the running job finished")
+	case <-time.After(time.Millisecond * 10):
+	}
+	close(release)
+	assert.NoError(t, <-stopped)
+}
+
+func TestSchedulerStopCancels(t *testing.T) {
+	s, clock := startScheduler(t)
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "* * * * *", clock), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil
+	}))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.Stop(ctx))
+	assert.Equal(t, context.Canceled, <-cancelled)
+}
+
+func TestSchedulerStopBeforeStart(t *testing.T) {
+	s := NewScheduler()
+	assert.NoError(t, s.Stop(context.Background()))
+	assert.NoError(t, s.Stop(context.Background()))
+
+	// Starting a stopped scheduler returns straight away
+	s.Start()
+}
+
+func TestJobQueue(t *testing.T) {
+	var q jobQueue
+	jobs := make([]*job, 5)
+	for i, offset := range []int{3, 1, 4, 0, 2} {
+		jobs[i] = &job{next: epoch.Add(time.Minute * time.Duration(offset))}
+		heap.Push(&q, jobs[i])
+	}
+	heap.Remove(&q, jobs[2].index)
+	assert.Equal(t, -1, jobs[2].index)
+
+	var order []time.Time
+	for q.Len() > 0 {
+		order = append(order, heap.Pop(&q).(*job).next)
+	}
+	assert.Equal(t, []time.Time{epoch, epoch.Add(time.Minute), epoch.Add(time.Minute * 2), epoch.Add(time.Minute * 3)}, order)
+}
@@ -0,0 +1,215 @@
+package tokei
+
+import "time"
+
+// Recurrence is anything which can find the next time it occurs.
+// Schedule and the composites created by Union, Intersect and Except are all Recurrences.
+type Recurrence interface {
+	// NextFrom returns the next time >= t which matches, or the zero time if nothing matches.
+	NextFrom(t time.Time) time.Time
+}
+
+// searchYears limits how far ahead schedules and composites search before deciding nothing matches.
+// Impossible dates, intersections and exclusions can rule out every time, which would otherwise search forever.
+const searchYears = 100
+
+// setOperation is the way a composite combines its members.
+type setOperation int
+
+// Types of setOperation
+const (
+	unionOperation setOperation = iota
+	intersectOperation
+	exceptOperation
+)
+
+// Composite is a Recurrence built from other Recurrences using a set operation.
+type Composite struct {
+	operation setOperation
+	members   []Recurrence
+}
+
+// Union creates a Recurrence which matches any time matched by at least one of its members.
+func Union(members ...Recurrence) *Composite {
+	return &Composite{operation: unionOperation, members: members}
+}
+
+// Intersect creates a Recurrence which matches times matched by all of its members.
+func Intersect(members ...Recurrence) *Composite {
+	return &Composite{operation: intersectOperation, members: members}
+}
+
+// Except creates a Recurrence which matches times matched by base but by none of excluded.
+func Except(base Recurrence, excluded ...Recurrence) *Composite {
+	return &Composite{operation: exceptOperation, members: append([]Recurrence{base}, excluded...)}
+}
+
+// clocked is implemented by recurrences which tell the current time with a Clock.
+type clocked interface {
+	now() time.Time
+}
+
+// now returns the current time from the clock of the first member which has one, which for a Schedule is set by
+// WithClock, or from SystemClock if none do.
+func (c *Composite) now() time.Time {
+	for _, member := range c.members {
+		if m, ok := member.(clocked); ok {
+			return m.now()
+		}
+	}
+	return SystemClock.Now()
+}
+
+// Next returns the next time that matches the composite, or the zero time if nothing does.
+// The current time is read from the clock of its first member which has one.
+func (c *Composite) Next() time.Time {
+	return c.NextFrom(c.now())
+}
+
+// NextFrom returns the next time >= t which matches the composite, or the zero time if nothing does.
+func (c *Composite) NextFrom(t time.Time) time.Time {
+	switch c.operation {
+	case unionOperation:
+		return c.union(t)
+	case intersectOperation:
+		return c.intersect(t)
+	default:
+		return c.except(t)
+	}
+}
+
+// Project returns up to the next N times that the composite is matched.
+func (c *Composite) Project(n int) []time.Time {
+	return c.ProjectFrom(c.now(), n)
+}
+
+// ProjectFrom returns up to the next N matching times after t. If t matches the composite,
+// it is counted in the results. Fewer than N times are returned if the composite stops matching.
+func (c *Composite) ProjectFrom(t time.Time, n int) []time.Time {
+	results := make([]time.Time, 0, n)
+	for next := c.NextFrom(t); len(results) < n && !next.IsZero(); next = c.NextFrom(after(next)) {
+		results = append(results, next)
+	}
+	return results
+}
+
+// union takes the earliest time from any member.
+func (c *Composite) union(t time.Time) time.Time {
+	var earliest time.Time
+	for _, member := range c.members {
+		next := member.NextFrom(t)
+		if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
+			earliest = next
+		}
+	}
+	return earliest
+}
+
+// intersect moves every member forward to the latest of their next times until they all agree.
+func (c *Composite) intersect(t time.Time) time.Time {
+	limit := t.AddDate(searchYears, 0, 0)
+	candidate := t
+	for candidate.Before(limit) {
+		matched := true
+		for _, member := range c.members {
+			next := member.NextFrom(candidate)
+			if next.IsZero() {
+				return time.Time{}
+			}
+			if next.After(candidate) {
+				candidate = next
+				matched = false
+			}
+		}
+		if matched {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+// except takes times from the first member, skipping any which another member matches.
+// When a time is excluded it jumps past the end of the excluded period, rather than trying each time in it.
+func (c *Composite) except(t time.Time) time.Time {
+	base, excluded := c.members[0], c.members[1:]
+	limit := t.AddDate(searchYears, 0, 0)
+	for candidate := base.NextFrom(t); !candidate.IsZero() && candidate.Before(limit); {
+		if matchesAny(excluded, candidate) == nil {
+			return candidate
+		}
+		candidate = base.NextFrom(unionRunEnd(excluded, candidate, limit))
+	}
+	return time.Time{}
+}
+
+// matchesAny returns the first recurrence which matches t, or nil if none do.
+func matchesAny(recurrences []Recurrence, t time.Time) Recurrence {
+	for _, recurrence := range recurrences {
+		if recurrence.NextFrom(t).Equal(t) {
+			return recurrence
+		}
+	}
+	return nil
+}
+
+// runEnder is implemented by recurrences which can find the end of a run of consecutive matches
+// without trying each time in it.
+type runEnder interface {
+	// runEnd returns the first whole minute after t, which it matches, that it doesn't match,
+	// or limit if it matches every minute until then.
+	runEnd(t, limit time.Time) time.Time
+}
+
+// runEnd returns a time after t such that r matches every minute from t up to it, given that r matches t.
+// Recurrences which don't implement runEnder only match t itself, as far as we know.
+func runEnd(r Recurrence, t, limit time.Time) time.Time {
+	if ender, ok := r.(runEnder); ok {
+		return ender.runEnd(t, limit)
+	}
+	return after(t)
+}
+
+// unionRunEnd returns the end of the run of times from t which at least one of the recurrences matches.
+func unionRunEnd(recurrences []Recurrence, t, limit time.Time) time.Time {
+	end := t
+	for end.Before(limit) {
+		matched := matchesAny(recurrences, end)
+		if matched == nil {
+			return end
+		}
+		end = runEnd(matched, end, limit)
+	}
+	return limit
+}
+
+// runEnd returns the first whole minute after t that the composite doesn't match, or limit if it matches every
+// minute until then.
+func (c *Composite) runEnd(t, limit time.Time) time.Time {
+	switch c.operation {
+	case unionOperation:
+		return unionRunEnd(c.members, t, limit)
+	case intersectOperation:
+		// The run ends as soon as any member's does.
+		end := limit
+		for _, member := range c.members {
+			if memberEnd := runEnd(member, t, limit); memberEnd.Before(end) {
+				end = memberEnd
+			}
+		}
+		return end
+	default:
+		// The run ends when the base's does, or an excluded member next matches.
+		end := runEnd(c.members[0], t, limit)
+		for _, excluded := range c.members[1:] {
+			if next := excluded.NextFrom(t); !next.IsZero() && next.Before(end) {
+				end = next
+			}
+		}
+		return end
+	}
+}
+
+// after returns the earliest instant after t, so NextFrom(after(t)) finds matches strictly after t.
+func after(t time.Time) time.Time {
+	return t.Add(time.Nanosecond)
+}
@@ -0,0 +1,169 @@
+package tokei
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustSchedule(t testing.TB, input string) *Schedule {
+	ex, err := Parse(input)
+	require.NoError(t, err)
+	return NewScheduleUTC(ex)
+}
+
+func TestUnion(t *testing.T) {
+	morning := mustSchedule(t, "0 9 * * *")
+	evening := mustSchedule(t, "30 17 * * *")
+	union := Union(morning, evening)
+
+	expected := []time.Time{
+		epoch.Add(time.Hour * 9),
+		epoch.Add(time.Hour*17 + time.Minute*30),
+		epoch.AddDate(0, 0, 1).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, union.ProjectFrom(epoch, 3))
+}
+
+func TestUnionDuplicates(t *testing.T) {
+	union := Union(mustSchedule(t, "*/15 * * * *"), mustSchedule(t, "*/10 * * * *"))
+
+	expected := []time.Time{
+		epoch,
+		epoch.Add(time.Minute * 10),
+		epoch.Add(time.Minute * 15),
+		epoch.Add(time.Minute * 20),
+		epoch.Add(time.Minute * 30),
+	}
+	assert.Equal(t, expected, union.ProjectFrom(epoch, 5))
+}
+
+func TestIntersect(t *testing.T) {
+	// Every 15 minutes during business hours. Epoch was a Thursday.
+	intersect := Intersect(mustSchedule(t, "*/15 * * * *"), mustSchedule(t, "* 9-17 * * 1-5"))
+
+	assert.Equal(t, epoch.Add(time.Hour*9), intersect.NextFrom(epoch))
+	assert.Equal(t, epoch.Add(time.Hour*9+time.Minute*15), intersect.NextFrom(epoch.Add(time.Hour*9+time.Minute)))
+
+	// Friday 17:45 is followed by Monday 09:00
+	friday := epoch.AddDate(0, 0, 1).Add(time.Hour*17 + time.Minute*46)
+	assert.Equal(t, epoch.AddDate(0, 0, 4).Add(time.Hour*9), intersect.NextFrom(friday))
+}
+
+func TestIntersectNever(t *testing.T) {
+	intersect := Intersect(mustSchedule(t, "* * * 1 *"), mustSchedule(t, "* * * 2 *"))
+	assert.True(t, intersect.NextFrom(epoch).IsZero())
+	assert.Empty(t, intersect.ProjectFrom(epoch, 3))
+}
+
+func TestExcept(t *testing.T) {
+	// Daily at 9, except on the 2nd and 3rd of the month.
+	except := Except(mustSchedule(t, "0 9 * * *"), mustSchedule(t, "* * 2 * *"), mustSchedule(t, "* * 3 * *"))
+
+	expected := []time.Time{
+		epoch.Add(time.Hour * 9),
+		epoch.AddDate(0, 0, 3).Add(time.Hour * 9),
+		epoch.AddDate(0, 0, 4).Add(time.Hour * 9),
+	}
+	assert.Equal(t, expected, except.ProjectFrom(epoch, 3))
+}
+
+func TestExceptEverything(t *testing.T) {
+	except := Except(mustSchedule(t, "0 9 * 1 *"), mustSchedule(t, "* * * 1 *"))
+	assert.True(t, except.NextFrom(epoch).IsZero())
+}
+
+func TestCompositeNested(t *testing.T) {
+	// Every 15 minutes during business hours plus midnight, except on Christmas day.
+	business := Intersect(mustSchedule(t, "*/15 * * * *"), mustSchedule(t, "* 9-17 * * 1-5"))
+	composite := Except(Union(business, mustSchedule(t, "0 0 * * *")), mustSchedule(t, "* * 25 12 *"))
+
+	christmasEve := time.Date(1970, 12, 24, 23, 59, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(1970, 12, 26, 0, 0, 0, 0, time.UTC), composite.NextFrom(christmasEve))
+
+	// The 28th was a Monday.
+	assert.Equal(t, time.Date(1970, 12, 28, 9, 0, 0, 0, time.UTC), composite.NextFrom(time.Date(1970, 12, 28, 0, 1, 0, 0, time.UTC)))
+}
+
+func TestCompositeClock(t *testing.T) {
+	// The current time comes from the clock of the first member, including in nested composites
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	quarters := mustScheduleClock(t, "*/15 * * * *", clock)
+	composite := Except(Union(quarters, mustSchedule(t, "0 9 * * *")), mustSchedule(t, "30 * * * *"))
+
+	assert.Equal(t, epoch.Add(time.Minute*15), composite.Next())
+	assert.Equal(t, []time.Time{epoch.Add(time.Minute * 15), epoch.Add(time.Minute * 45)}, composite.Project(2))
+
+	clock.Set(epoch.Add(time.Minute * 50))
+	assert.Equal(t, epoch.Add(time.Hour), composite.Next())
+}
+
+func TestExceptLongExclusion(t *testing.T) {
+	// Every minute, except for the first half of the year and any Monday.
+	except := Except(mustSchedule(t, "* * * * *"), mustSchedule(t, "* * * 1-6 *"), mustSchedule(t, "* * * * 1"))
+
+	// The 1st of July 1970 was a Wednesday.
+	assert.Equal(t, time.Date(1970, 7, 1, 0, 0, 0, 0, time.UTC), except.NextFrom(epoch))
+	// The 6th of July 1970 was a Monday.
+	assert.Equal(t, time.Date(1970, 7, 7, 0, 0, 0, 0, time.UTC), except.NextFrom(time.Date(1970, 7, 6, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestExceptNestedExclusion(t *testing.T) {
+	// Every 15 minutes, except during business hours outside of lunch, with NotAfter ending the exclusion.
+	lunch := mustSchedule(t, "* 12 * * *")
+	business := Except(Intersect(mustSchedule(t, "* 9-17 * * *"), mustSchedule(t, "* * * * *")), lunch)
+	except := Except(mustSchedule(t, "*/15 * * * *"), business)
+
+	expected := []time.Time{
+		epoch.Add(time.Hour * 12),
+		epoch.Add(time.Hour*12 + time.Minute*15),
+		epoch.Add(time.Hour*12 + time.Minute*30),
+		epoch.Add(time.Hour*12 + time.Minute*45),
+		epoch.Add(time.Hour * 18),
+	}
+	assert.Equal(t, expected, except.ProjectFrom(epoch.Add(time.Hour*9), 5))
+
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	bounded := Except(mustSchedule(t, "*/15 * * * *"), NewScheduleUTC(ex, NotAfter(epoch.Add(time.Hour+time.Minute*20))))
+	assert.Equal(t, epoch.Add(time.Hour+time.Minute*30), bounded.NextFrom(epoch))
+}
+
+// recurrenceFunc is a Recurrence which isn't a Schedule or Composite.
+type recurrenceFunc func(t time.Time) time.Time
+
+func (f recurrenceFunc) NextFrom(t time.Time) time.Time {
+	return f(t)
+}
+
+func TestExceptUnknownRecurrence(t *testing.T) {
+	// Other recurrences are tried one time at a time.
+	weekdays := mustSchedule(t, "* * * * 1-5")
+	except := Except(mustSchedule(t, "0 9 * * *"), recurrenceFunc(weekdays.NextFrom))
+	// Epoch was a Thursday, so the next weekend day is Saturday.
+	assert.Equal(t, epoch.AddDate(0, 0, 2).Add(time.Hour*9), except.NextFrom(epoch))
+}
+
+// BenchmarkExcept measures finding the next time past long exclusions.
+func BenchmarkExcept(b *testing.B) {
+	cases := []struct {
+		name     string
+		excluded []Recurrence
+	}{
+		{"half year", []Recurrence{mustSchedule(b, "* * * 1-6 *")}},
+		{"business hours", []Recurrence{mustSchedule(b, "* 9-17 * * 1-5")}},
+		{"nested", []Recurrence{Union(mustSchedule(b, "* * 1-20 * *"), Intersect(mustSchedule(b, "* * * 1-6 *"), mustSchedule(b, "* 0-22 * * *")))}},
+	}
+
+	base := mustSchedule(b, "* * * * *")
+	for _, bench := range cases {
+		except := Except(base, bench.excluded...)
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				except.NextFrom(epoch)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package tokei
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotOwner is returned when releasing a job which is claimed by a different owner, or not claimed at all.
+var ErrNotOwner = errors.New("job is not claimed by this owner")
+
+// SQLDialect describes how a database differs from the SQL which SQLStore otherwise uses.
+type SQLDialect struct {
+	name string
+	// numbered databases use $1, $2 placeholders rather than ?.
+	numbered bool
+	// upsert is the clause which turns an insert of a job into an update if it already exists.
+	upsert string
+	// skipLocked databases can lock the jobs they are claiming and skip those locked by others.
+	skipLocked bool
+	// insertIgnore and ignoreConflict make an insert do nothing if the row already exists.
+	insertIgnore, ignoreConflict string
+	// indexIfNotExists databases support CREATE INDEX IF NOT EXISTS. Others are checked for the index first.
+	indexIfNotExists bool
+}
+
+// Dialects supported by SQLStore.
+var (
+	Postgres = SQLDialect{
+		name:             "postgres",
+		numbered:         true,
+		upsert:           "ON CONFLICT (id) DO UPDATE SET definition = excluded.definition, version = tokei_jobs.version + 1",
+		skipLocked:       true,
+		insertIgnore:     "INSERT INTO",
+		ignoreConflict:   "ON CONFLICT DO NOTHING",
+		indexIfNotExists: true,
+	}
+	MySQL = SQLDialect{
+		name:         "mysql",
+		upsert:       "ON DUPLICATE KEY UPDATE definition = VALUES(definition), version = version + 1",
+		skipLocked:   true,
+		insertIgnore: "INSERT IGNORE INTO",
+	}
+	SQLite = SQLDialect{
+		name:             "sqlite",
+		upsert:           "ON CONFLICT (id) DO UPDATE SET definition = excluded.definition, version = tokei_jobs.version + 1",
+		insertIgnore:     "INSERT INTO",
+		ignoreConflict:   "ON CONFLICT DO NOTHING",
+		indexIfNotExists: true,
+	}
+)
+
+// String returns the name of the dialect.
+func (d SQLDialect) String() string {
+	return d.name
+}
+
+// rebind rewrites the ? placeholders in query for the dialect.
+func (d SQLDialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// migration is a change to the tables used by SQLStore.
+type migration struct {
+	statement string
+	// index is the name of the index the statement creates, if it creates one, and table is the table it is on.
+	index, table string
+}
+
+// migrations create and update the tables used by SQLStore. Each one is applied once, in order, and new ones must
+// only ever be added to the end. Some databases, such as MySQL, commit changes to tables straight away rather than
+// as part of a transaction, so a migration can be applied without being recorded. Each one must be safe to apply
+// again for the next Migrate to finish it.
+var migrations = []migration{
+	{statement: `CREATE TABLE IF NOT EXISTS tokei_jobs (
+		id VARCHAR(255) NOT NULL PRIMARY KEY,
+		definition TEXT NOT NULL,
+		last_run BIGINT,
+		next_run BIGINT,
+		owner VARCHAR(255) NOT NULL,
+		lease_until BIGINT NOT NULL,
+		version BIGINT NOT NULL
+	)`},
+	{statement: `CREATE INDEX tokei_jobs_next_run ON tokei_jobs (next_run)`, index: "tokei_jobs_next_run", table: "tokei_jobs"},
+	{statement: `CREATE TABLE IF NOT EXISTS tokei_locks (
+		id VARCHAR(255) NOT NULL,
+		scheduled BIGINT NOT NULL,
+		owner VARCHAR(255) NOT NULL,
+		expires BIGINT NOT NULL,
+		PRIMARY KEY (id, scheduled)
+	)`},
+}
+
+// apply applies the migration in tx, doing nothing if it has already been applied.
+func (m migration) apply(ctx context.Context, tx *sql.Tx, dialect SQLDialect) error {
+	if m.index == "" {
+		_, err := tx.ExecContext(ctx, m.statement)
+		return err
+	}
+	if dialect.indexIfNotExists {
+		_, err := tx.ExecContext(ctx, strings.Replace(m.statement, "CREATE INDEX", "CREATE INDEX IF NOT EXISTS", 1))
+		return err
+	}
+	var n int
+	err := tx.QueryRowContext(ctx, dialect.rebind(`SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?`), m.table, m.index).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, m.statement)
+	return err
+}
+
+// SQLStore is a Store which keeps jobs in a SQL database, so that they can be shared between the nodes of a cluster.
+// Times are stored as Unix nanoseconds, with NULL for the zero time.
+//
+// A Scheduler only saves and restores its jobs in the store, and schedulers which run the same jobs on several nodes
+// use a Locker such as SQLLocker so that only one of them runs each time. Claim and Release are for running the
+// stored jobs without a Scheduler instead: each worker repeatedly claims the jobs which are due, runs them and
+// releases them with when they are next due, so that only one worker runs each one.
+type SQLStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+}
+
+// NewSQLStore creates a store in db, which is a database of the given dialect. Migrate must be called before the
+// store is used to create its tables.
+func NewSQLStore(db *sql.DB, dialect SQLDialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect}
+}
+
+// Migrate creates or updates the store's tables, applying any migrations which haven't been already. Each migration
+// is recorded in the tokei_migrations table in the same transaction as it is applied, but databases which commit
+// changes to tables straight away, such as MySQL, can't undo a migration if recording it fails. Migrations are safe
+// to apply again, so calling Migrate again after an error finishes them.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS tokei_migrations (version INTEGER NOT NULL PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+	var applied int
+	err = s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM tokei_migrations`).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	for version := applied + 1; version <= len(migrations); version++ {
+		err := s.transact(ctx, func(tx *sql.Tx) error {
+			if err := migrations[version-1].apply(ctx, tx, s.dialect); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO tokei_migrations (version) VALUES (?)`), version)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save adds a job or replaces its definition. The job's owner and runs aren't changed.
+func (s *SQLStore) Save(record JobRecord) error {
+	if err := record.storable(); err != nil {
+		return err
+	}
+	definition, err := json.Marshal(definitionOf(record))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.dialect.rebind(`INSERT INTO tokei_jobs (id, definition, last_run, next_run, owner, lease_until, version)
+		VALUES (?, ?, ?, ?, '', 0, 0) `+s.dialect.upsert),
+		record.ID, string(definition), nullTime(record.LastRun), nullTime(record.NextRun))
+	return err
+}
+
+// Delete removes a job.
+func (s *SQLStore) Delete(id string) error {
+	_, err := s.db.Exec(s.dialect.rebind(`DELETE FROM tokei_jobs WHERE id = ?`), id)
+	return err
+}
+
+// Jobs returns every job, ordered by ID.
+func (s *SQLStore) Jobs() ([]JobRecord, error) {
+	rows, err := s.db.Query(`SELECT id, definition, last_run, next_run, owner FROM tokei_jobs ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []JobRecord
+	for rows.Next() {
+		var record JobRecord
+		var definition string
+		var last, next sql.NullInt64
+		if err := rows.Scan(&record.ID, &definition, &last, &next, &record.Owner); err != nil {
+			return nil, err
+		}
+		if err := record.load(definition, last, next); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// SetRun records when a job last ran and is next due.
+func (s *SQLStore) SetRun(id string, last, next time.Time) error {
+	result, err := s.db.Exec(s.dialect.rebind(`UPDATE tokei_jobs SET last_run = ?, next_run = ?, version = version + 1 WHERE id = ?`),
+		nullTime(last), nullTime(next), id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, ErrJobNotFound)
+}
+
+// Claim takes ownership of up to limit jobs which are due at now, until now + lease, and returns them in the order
+// they are due. Jobs which are claimed by another owner whose lease hasn't expired are skipped, while those already
+// claimed by owner are returned again, so a worker which restarts carries on with the jobs it had claimed.
+// Each claimed job should be released once it has run.
+//
+// Databases which support it lock the rows they are claiming with SELECT ... FOR UPDATE SKIP LOCKED, so that nodes
+// claiming at the same time don't wait for each other. Others, such as SQLite, only claim a job if its version hasn't
+// changed since it was selected, so a job claimed by someone else in the meantime is skipped.
+func (s *SQLStore) Claim(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]JobRecord, error) {
+	query := `SELECT id, definition, last_run, next_run, version FROM tokei_jobs
+		WHERE next_run IS NOT NULL AND next_run <= ? AND (owner = '' OR owner = ? OR lease_until <= ?)
+		ORDER BY next_run LIMIT ?`
+	if s.dialect.skipLocked {
+		query += ` FOR UPDATE SKIP LOCKED`
+	}
+	at := now.UnixNano()
+
+	var claimed []JobRecord
+	err := s.transact(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, s.dialect.rebind(query), at, owner, at, limit)
+		if err != nil {
+			return err
+		}
+		var candidates []JobRecord
+		var versions []int64
+		for rows.Next() {
+			var record JobRecord
+			var definition string
+			var last, next sql.NullInt64
+			var version int64
+			if err := rows.Scan(&record.ID, &definition, &last, &next, &version); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := record.load(definition, last, next); err != nil {
+				rows.Close()
+				return err
+			}
+			candidates = append(candidates, record)
+			versions = append(versions, version)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i, record := range candidates {
+			result, err := tx.ExecContext(ctx, s.dialect.rebind(`UPDATE tokei_jobs SET owner = ?, lease_until = ?, version = version + 1
+				WHERE id = ? AND version = ?`), owner, now.Add(lease).UnixNano(), record.ID, versions[i])
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				// Someone else claimed the job since it was selected.
+				continue
+			}
+			record.Owner = owner
+			claimed = append(claimed, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// Release gives up ownership of a claimed job, recording when it last ran and is next due.
+// It returns ErrNotOwner if the job isn't claimed by owner, such as when its lease expired and another owner claimed it.
+func (s *SQLStore) Release(ctx context.Context, id, owner string, last, next time.Time) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`UPDATE tokei_jobs
+		SET last_run = ?, next_run = ?, owner = '', lease_until = 0, version = version + 1
+		WHERE id = ? AND owner = ?`), nullTime(last), nullTime(next), id, owner)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, ErrNotOwner)
+}
+
+// SQLLocker is a Locker which takes leases on runs in the tokei_locks table of a SQLStore's database.
+type SQLLocker struct {
+	store *SQLStore
+	owner string
+	clock Clock
+}
+
+// NewSQLLocker creates a locker which takes leases in store's database, recording owner as the holder.
+// The store must have been migrated. Leases expire according to clock, which defaults to SystemClock if nil,
+// so the clocks of the schedulers sharing the database shouldn't be further apart than the lease TTL.
+func NewSQLLocker(store *SQLStore, owner string, clock Clock) *SQLLocker {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &SQLLocker{store: store, owner: owner, clock: clock}
+}
+
+// Lock takes the lease on a run of a job unless someone else holds it. Expired leases are deleted first.
+func (l *SQLLocker) Lock(ctx context.Context, id string, scheduled time.Time, ttl time.Duration) (bool, error) {
+	now := l.clock.Now()
+	dialect := l.store.dialect
+	_, err := l.store.db.ExecContext(ctx, dialect.rebind(`DELETE FROM tokei_locks WHERE expires <= ?`), now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	result, err := l.store.db.ExecContext(ctx, dialect.rebind(dialect.insertIgnore+` tokei_locks (id, scheduled, owner, expires)
+		VALUES (?, ?, ?, ?) `+dialect.ignoreConflict), id, scheduled.UnixNano(), l.owner, now.Add(ttl).UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// transact runs f in a transaction, which is committed if f succeeds and rolled back otherwise.
+func (s *SQLStore) transact(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// expectRow returns notFound unless the statement affected a row.
+func expectRow(result sql.Result, notFound error) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// definitionOf returns the parts of a record which are stored as its definition, leaving out those with their own columns.
+func definitionOf(record JobRecord) JobRecord {
+	record.ID = ""
+	record.LastRun, record.NextRun = time.Time{}, time.Time{}
+	record.Owner = ""
+	return record
+}
+
+// load fills in a record from its stored columns.
+func (r *JobRecord) load(definition string, last, next sql.NullInt64) error {
+	id, owner := r.ID, r.Owner
+	if err := json.Unmarshal([]byte(definition), r); err != nil {
+		return err
+	}
+	r.ID, r.Owner = id, owner
+	r.LastRun, r.NextRun = fromNullTime(last), fromNullTime(next)
+	return nil
+}
+
+// nullTime converts t to Unix nanoseconds, storing the zero time as NULL.
+func nullTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+// fromNullTime converts Unix nanoseconds to a time in UTC, with NULL as the zero time.
+func fromNullTime(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+	return time.Unix(0, n.Int64).UTC()
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+// +build sqlite
+
+package tokei
+
+// Registers the pure Go SQLite driver, so that the SQL store tests can run with go test -tags sqlite.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,204 @@
+package tokei
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openSQLite opens a migrated store in a new SQLite database. The tests which need a database are skipped unless a
+// driver is registered as "sqlite", which is done when testing with -tags sqlite. The rest always run.
+func openSQLite(t *testing.T) *SQLStore {
+	registered := false
+	for _, driver := range sql.Drivers() {
+		registered = registered || driver == "sqlite"
+	}
+	if !registered {
+		t.Skip("no sqlite driver registered, test with -tags sqlite")
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "tokei.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	store := NewSQLStore(db, SQLite)
+	require.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+func TestSQLStore(t *testing.T) {
+	testStore(t, openSQLite(t))
+}
+
+func TestSQLStoreMigrate(t *testing.T) {
+	store := openSQLite(t)
+
+	// Migrations which have been applied aren't applied again
+	require.NoError(t, store.Migrate(context.Background()))
+	var version int
+	require.NoError(t, store.db.QueryRow(`SELECT MAX(version) FROM tokei_migrations`).Scan(&version))
+	assert.Equal(t, len(migrations), version)
+
+	// Migrations which were applied without being recorded can be applied again
+	_, err := store.db.Exec(`DELETE FROM tokei_migrations WHERE version > 1`)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.Background()))
+	require.NoError(t, store.db.QueryRow(`SELECT MAX(version) FROM tokei_migrations`).Scan(&version))
+	assert.Equal(t, len(migrations), version)
+}
+
+func TestMigrationsRepeatable(t *testing.T) {
+	for _, m := range migrations {
+		if m.index != "" {
+			assert.True(t, strings.HasPrefix(m.statement, "CREATE INDEX "+m.index+" ON "+m.table+" "), m.statement)
+			continue
+		}
+		assert.Contains(t, m.statement, "IF NOT EXISTS")
+	}
+}
+
+func TestSQLStoreClaim(t *testing.T) {
+	ctx := context.Background()
+	store := openSQLite(t)
+	hourly := mustSchedule(t, "0 * * * *")
+	for i, id := range []string{"late", "early", "later", "done"} {
+		next := epoch.Add(time.Hour * time.Duration(i))
+		if id == "done" {
+			next = time.Time{}
+		}
+		require.NoError(t, store.Save(JobRecord{ID: id, Schedule: hourly, NextRun: next}))
+	}
+	require.NoError(t, store.SetRun("early", time.Time{}, epoch.Add(-time.Hour)))
+
+	// Due jobs are claimed in the order they are due, up to the limit
+	claimed, err := store.Claim(ctx, "node-1", epoch.Add(time.Hour*2), time.Minute, 2)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "early", claimed[0].ID)
+	assert.Equal(t, "late", claimed[1].ID)
+	assert.Equal(t, "node-1", claimed[0].Owner)
+	assert.True(t, claimed[0].Schedule.Equal(hourly))
+
+	// Other nodes can't claim them until the lease expires
+	claimed, err = store.Claim(ctx, "node-2", epoch.Add(time.Hour*2), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "later", claimed[0].ID)
+
+	claimed, err = store.Claim(ctx, "node-2", epoch.Add(time.Hour*2+time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+
+	// Only the current owner can release a job
+	assert.Equal(t, ErrNotOwner, store.Release(ctx, "early", "node-1", epoch, epoch.Add(time.Hour*3)))
+	require.NoError(t, store.Release(ctx, "early", "node-2", epoch, epoch.Add(time.Hour*3)))
+
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	assert.Equal(t, "early", records[1].ID)
+	assert.Equal(t, "", records[1].Owner)
+	assert.Equal(t, epoch, records[1].LastRun)
+	assert.Equal(t, epoch.Add(time.Hour*3), records[1].NextRun)
+	assert.Equal(t, "node-2", records[2].Owner)
+}
+
+func TestSQLStoreWorkers(t *testing.T) {
+	ctx := context.Background()
+	store := openSQLite(t)
+	hourly := mustSchedule(t, "0 * * * *")
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Save(JobRecord{ID: id, Schedule: hourly, NextRun: epoch.Add(time.Hour)}))
+	}
+
+	// Workers claim the jobs which are due, one at a time, and release them once they have run
+	runs := make(map[string][]time.Time)
+	work := func(owner string, now time.Time) bool {
+		claimed, err := store.Claim(ctx, owner, now, time.Minute, 1)
+		require.NoError(t, err)
+		if len(claimed) == 0 {
+			return false
+		}
+		job := claimed[0]
+		runs[job.ID] = append(runs[job.ID], job.NextRun)
+		require.NoError(t, store.Release(ctx, job.ID, owner, job.NextRun, job.Schedule.NextFrom(after(now))))
+		return true
+	}
+	for _, now := range []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2)} {
+		for {
+			first := work("node-1", now)
+			second := work("node-2", now)
+			if !first && !second {
+				break
+			}
+		}
+	}
+
+	// Each job runs once at each time it is due
+	for _, id := range []string{"a", "b", "c"} {
+		assert.Equal(t, []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2)}, runs[id], id)
+	}
+}
+
+func TestSQLLocker(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	store := openSQLite(t)
+	testLocker(t, NewSQLLocker(store, "node-1", clock), clock)
+
+	// Locks are shared with other owners
+	ok, err := NewSQLLocker(store, "node-2", clock).Lock(context.Background(), "job", epoch, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSQLRebind(t *testing.T) {
+	query := `UPDATE tokei_jobs SET owner = ? WHERE id = ? AND version = ?`
+	assert.Equal(t, `UPDATE tokei_jobs SET owner = $1 WHERE id = $2 AND version = $3`, Postgres.rebind(query))
+	assert.Equal(t, query, MySQL.rebind(query))
+	assert.Equal(t, query, SQLite.rebind(query))
+}
+
+func TestJobRecordDefinition(t *testing.T) {
+	record := JobRecord{
+		ID:       "job",
+		Schedule: mustSchedule(t, "0 * * * *"),
+		Overlap:  OverlapForbid,
+		Priority: 2,
+		Timeout:  time.Minute,
+		Retry:    RetryPolicy{Attempts: 3, Backoff: time.Second},
+		Misfire:  MisfireFireAll,
+		LastRun:  epoch,
+		NextRun:  epoch.Add(time.Hour),
+		Owner:    "node-1",
+	}
+
+	// The columns aren't repeated in the definition
+	definition, err := json.Marshal(definitionOf(record))
+	require.NoError(t, err)
+	assert.NotContains(t, string(definition), "node-1")
+	assert.NotContains(t, string(definition), epoch.Format(time.RFC3339))
+
+	loaded := JobRecord{ID: "job", Owner: "node-1"}
+	require.NoError(t, loaded.load(string(definition), nullTime(epoch), nullTime(epoch.Add(time.Hour))))
+	assert.True(t, record.Schedule.Equal(loaded.Schedule))
+	loaded.Schedule = record.Schedule
+	assert.Equal(t, record, loaded)
+
+	assert.Error(t, loaded.load("{", sql.NullInt64{}, sql.NullInt64{}))
+}
+
+func TestNullTime(t *testing.T) {
+	assert.False(t, nullTime(time.Time{}).Valid)
+	assert.True(t, fromNullTime(sql.NullInt64{}).IsZero())
+
+	assert.Equal(t, epoch, fromNullTime(nullTime(epoch)))
+	now := time.Date(2020, time.March, 1, 12, 30, 0, 5, time.UTC)
+	assert.Equal(t, now, fromNullTime(nullTime(now)))
+}
@@ -0,0 +1,399 @@
+package tokei
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned by a Store when there's no job with the ID.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobRecord is the stored form of a job added to a Scheduler. The schedule is stored as its expression, location
+// and bounds. Schedules with a calendar, or in a location which can't be loaded by name such as a fixed zone, can't
+// be stored, so stores return an error when saving them.
+type JobRecord struct {
+	ID       string        `json:"id"`
+	Schedule *Schedule     `json:"schedule"`
+	Overlap  OverlapPolicy `json:"overlap,omitempty"`
+	Priority int           `json:"priority,omitempty"`
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Retry    RetryPolicy   `json:"retry"`
+	Misfire  MisfirePolicy `json:"misfire,omitempty"`
+
+	// LastRun is the scheduled time of the job's last run, and NextRun is when it is next due.
+	// Either is zero if there isn't one.
+	LastRun time.Time `json:"lastRun,omitempty"`
+	NextRun time.Time `json:"nextRun,omitempty"`
+
+	// Owner is who has claimed the job, in stores which can be shared between nodes such as SQLStore.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Store saves a Scheduler's jobs and when they last ran, so that they can be restored when it restarts.
+// Stores must be safe to use from multiple goroutines.
+type Store interface {
+	// Save adds a job, or replaces the definition of the job with the same ID. A replaced job keeps its stored last
+	// and next runs, so that adding a job again when the scheduler restarts doesn't lose when it last ran. It returns
+	// an error if the job's schedule can't be stored, such as when it has a calendar.
+	Save(record JobRecord) error
+	// Delete removes a job. Deleting a job which isn't stored isn't an error.
+	Delete(id string) error
+	// Jobs returns every stored job, ordered by ID.
+	Jobs() ([]JobRecord, error)
+	// SetRun records the scheduled time of a job's last run and when it is next due.
+	// It returns ErrJobNotFound if the job isn't stored.
+	SetRun(id string, last, next time.Time) error
+}
+
+// WithStore saves jobs to store when they are added, removed or run. Stored jobs can be added back to the
+// scheduler with Restore.
+func WithStore(store Store) SchedulerOption {
+	return func(s *Scheduler) {
+		s.store = store
+	}
+}
+
+// WithMissedRuns sets what a restored job does about times it was due while the scheduler wasn't running.
+// It defaults to MisfireFireOnce.
+func WithMissedRuns(policy MisfirePolicy) JobOption {
+	return func(j *job) {
+		j.misfire = policy
+	}
+}
+
+// record returns the stored form of the job.
+func (j *job) record() JobRecord {
+	return JobRecord{
+		ID:       j.id,
+		Schedule: j.schedule,
+		Overlap:  j.overlap,
+		Priority: j.priority,
+		Timeout:  j.timeout,
+		Retry:    j.retry,
+		Misfire:  j.misfire,
+		NextRun:  j.next,
+	}
+}
+
+// options returns the job options which were stored in the record.
+func (r JobRecord) options() []JobOption {
+	return []JobOption{
+		WithOverlapPolicy(r.Overlap),
+		WithPriority(r.Priority),
+		WithTimeout(r.Timeout),
+		WithRetry(r.Retry),
+		WithMissedRuns(r.Misfire),
+	}
+}
+
+// storable returns an error if the record's schedule can't be stored and restored without losing part of it.
+func (r JobRecord) storable() error {
+	if r.Schedule == nil {
+		return nil
+	}
+	_, err := r.Schedule.config()
+	return err
+}
+
+// runUpdate is a change to when a job last ran, to be saved to the store.
+type runUpdate struct {
+	id         string
+	last, next time.Time
+}
+
+// Restore adds the jobs saved in the scheduler's store, resuming from when they last ran, or from when they were next
+// due if they never ran. Times which were missed while the scheduler wasn't running are handled by each job's missed
+// runs policy, and only the latest 1000 are considered after a long outage.
+// lookup returns the function to run for each job, or nil to leave the job in the store without running it.
+// Jobs which have already been added, such as by AddJob, are left as they are. If a job can't be restored the rest
+// still are, and the first error is returned.
+func (s *Scheduler) Restore(lookup func(id string) JobFunc) error {
+	if s.store == nil {
+		return errors.New("scheduler has no store")
+	}
+	records, err := s.store.Jobs()
+	if err != nil {
+		return err
+	}
+	var updates []runUpdate
+	var first error
+	for _, record := range records {
+		run := lookup(record.ID)
+		if run == nil {
+			continue
+		}
+		update, err := s.restore(record, run)
+		if err == ErrJobExists {
+			continue
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+		if update != nil {
+			updates = append(updates, *update)
+		}
+	}
+	s.mu.Lock()
+	s.notify()
+	s.mu.Unlock()
+	s.flush()
+	if err := s.persist(updates); first == nil {
+		first = err
+	}
+	return first
+}
+
+// restore adds a stored job and triggers the runs it missed, returning the change to its run times if there was one.
+func (s *Scheduler) restore(record JobRecord, run JobFunc) (*runUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[record.ID]; ok {
+		return nil, ErrJobExists
+	}
+	if record.Schedule == nil {
+		return nil, errors.New("stored job " + record.ID + " is missing a schedule")
+	}
+	j := &job{id: record.ID, schedule: record.Schedule, run: run, index: -1}
+	for _, opt := range record.options() {
+		opt(j)
+	}
+	s.jobs[j.id] = j
+
+	now := s.clock.Now()
+	last := record.LastRun
+	from := now
+	if !from.After(last) {
+		from = after(last)
+	}
+	// The first time which could have been missed is the one after the last run, or the stored next run if the job
+	// hasn't run yet. Searching from it rather than using it directly copes with the schedule having changed.
+	first := record.NextRun
+	if !last.IsZero() {
+		first = after(last)
+	}
+	if !first.IsZero() {
+		first = j.schedule.NextFrom(first)
+	}
+	if first.IsZero() || first.After(now) {
+		s.enqueue(j, j.schedule.NextFrom(from))
+		return nil, nil
+	}
+
+	missed := dueTimes(j.schedule, first, now)
+	for _, scheduled := range missed {
+		s.record(misfireEvent, j, scheduled, 0)
+	}
+	for _, scheduled := range j.misfire.apply(missed, false) {
+		s.trigger(j, scheduled)
+	}
+	last = missed[len(missed)-1]
+	if !from.After(last) {
+		from = after(last)
+	}
+	s.enqueue(j, j.schedule.NextFrom(from))
+	return &runUpdate{id: j.id, last: last, next: j.next}, nil
+}
+
+// persist saves changes to when jobs ran to the store, reporting any errors to the error handler.
+// It returns the first error.
+func (s *Scheduler) persist(updates []runUpdate) error {
+	var first error
+	for _, update := range updates {
+		err := s.store.SetRun(update.id, update.last, update.next)
+		if err == nil || err == ErrJobNotFound {
+			// Jobs can be removed while their update is waiting to be saved.
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		if s.onErr != nil {
+			s.onErr(update.id, err)
+		}
+	}
+	return first
+}
+
+// MemoryStore is a Store which keeps jobs in memory. It is useful for tests and as a cache for other stores.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]JobRecord
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]JobRecord)}
+}
+
+// Save adds a job or replaces its definition.
+func (m *MemoryStore) Save(record JobRecord) error {
+	if err := record.storable(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.jobs[record.ID]; ok {
+		record.LastRun, record.NextRun = existing.LastRun, existing.NextRun
+	}
+	m.jobs[record.ID] = record
+	return nil
+}
+
+// Delete removes a job.
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+	return nil
+}
+
+// Jobs returns every job, ordered by ID.
+func (m *MemoryStore) Jobs() ([]JobRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := make([]JobRecord, 0, len(m.jobs))
+	for _, record := range m.jobs {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+// SetRun records when a job last ran and is next due.
+func (m *MemoryStore) SetRun(id string, last, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	record.LastRun, record.NextRun = last, next
+	m.jobs[id] = record
+	return nil
+}
+
+// copy returns a store with the same jobs.
+func (m *MemoryStore) copy() *MemoryStore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := NewMemoryStore()
+	for id, record := range m.jobs {
+		c.jobs[id] = record
+	}
+	return c
+}
+
+// replace replaces the jobs with those in other, which mustn't be changed afterwards.
+func (m *MemoryStore) replace(other *MemoryStore) {
+	jobs := other.jobs
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs = jobs
+}
+
+// FileStore is a Store which keeps jobs in a single JSON file. The whole file is rewritten on every change,
+// by writing a temporary file next to it and renaming it into place, so a crash never leaves it half written.
+type FileStore struct {
+	mu     sync.Mutex
+	path   string
+	memory *MemoryStore
+}
+
+// OpenFileStore opens the store in the file at path, which is created when the first job is saved if it doesn't exist.
+func OpenFileStore(path string) (*FileStore, error) {
+	f := &FileStore{path: path, memory: NewMemoryStore()}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []JobRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		f.memory.jobs[record.ID] = record
+	}
+	return f, nil
+}
+
+// Save adds a job or replaces its definition.
+func (f *FileStore) Save(record JobRecord) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.Save(record)
+	})
+}
+
+// Delete removes a job.
+func (f *FileStore) Delete(id string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.Delete(id)
+	})
+}
+
+// Jobs returns every job, ordered by ID.
+func (f *FileStore) Jobs() ([]JobRecord, error) {
+	return f.memory.Jobs()
+}
+
+// SetRun records when a job last ran and is next due.
+func (f *FileStore) SetRun(id string, last, next time.Time) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.SetRun(id, last, next)
+	})
+}
+
+// update applies a change to a copy of the jobs and writes them all to the file. The jobs in memory are only
+// replaced once the file has been written, so that they never differ from it.
+func (f *FileStore) update(change func(m *MemoryStore) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changed := f.memory.copy()
+	if err := change(changed); err != nil {
+		return err
+	}
+	records, err := changed.Jobs()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := f.write(data); err != nil {
+		return err
+	}
+	f.memory.replace(changed)
+	return nil
+}
+
+// write replaces the file with data.
+func (f *FileStore) write(data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
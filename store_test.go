@@ -0,0 +1,362 @@
+package tokei
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore checks the behaviour shared by every Store.
+func testStore(t *testing.T, store Store) {
+	hourly := mustSchedule(t, "0 * * * *")
+	require.NoError(t, store.Save(JobRecord{ID: "b", Schedule: hourly, Priority: 1}))
+	require.NoError(t, store.Save(JobRecord{ID: "a", Schedule: hourly}))
+	require.NoError(t, store.Save(JobRecord{ID: "c", Schedule: hourly}))
+	require.NoError(t, store.Delete("c"))
+	require.NoError(t, store.Delete("missing"))
+
+	require.NoError(t, store.SetRun("a", epoch, epoch.Add(time.Hour)))
+	assert.Equal(t, ErrJobNotFound, store.SetRun("missing", epoch, epoch))
+
+	// Saving a job again replaces its definition but keeps its runs
+	require.NoError(t, store.Save(JobRecord{ID: "a", Schedule: hourly, Retry: RetryPolicy{Attempts: 3, Backoff: time.Second},
+		NextRun: epoch.Add(time.Hour * 2)}))
+
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "a", records[0].ID)
+	assert.True(t, records[0].Schedule.Equal(hourly))
+	assert.Equal(t, RetryPolicy{Attempts: 3, Backoff: time.Second}, records[0].Retry)
+	assert.True(t, epoch.Equal(records[0].LastRun))
+	assert.True(t, epoch.Add(time.Hour).Equal(records[0].NextRun))
+	assert.Equal(t, "b", records[1].ID)
+	assert.Equal(t, 1, records[1].Priority)
+	assert.True(t, records[1].LastRun.IsZero())
+
+	// Schedules are stored with their bounds, but those with calendars can't be stored
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	bounded := NewScheduleUTC(ex, NotBefore(epoch.Add(time.Hour)), NotAfter(epoch.Add(time.Hour*3)))
+	require.NoError(t, store.Save(JobRecord{ID: "bounded", Schedule: bounded}))
+	records, err = store.Jobs()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, bounded.ProjectFrom(epoch, 5), records[2].Schedule.ProjectFrom(epoch, 5))
+	require.NoError(t, store.Delete("bounded"))
+
+	withCalendar := NewScheduleUTC(ex, WithCalendar(NewDateSet(epoch)))
+	assert.Equal(t, errCalendarSchedule, store.Save(JobRecord{ID: "calendar", Schedule: withCalendar}))
+	assert.Error(t, store.Save(JobRecord{ID: "fixed", Schedule: NewSchedule(time.FixedZone("UTC+2", 7200), ex)}))
+	records, err = store.Jobs()
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokei")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jobs.json")
+
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	testStore(t, store)
+
+	// Reopening the file gives the same jobs
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+	expected, err := store.Jobs()
+	require.NoError(t, err)
+	records, err := reopened.Jobs()
+	require.NoError(t, err)
+	require.Len(t, records, len(expected))
+	for i := range records {
+		assert.Equal(t, expected[i].ID, records[i].ID)
+		assert.True(t, expected[i].Schedule.Equal(records[i].Schedule))
+		assert.True(t, expected[i].LastRun.Equal(records[i].LastRun))
+	}
+
+	// Only the store's own file is left in the directory
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestFileStoreErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokei")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "jobs.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+	_, err = OpenFileStore(path)
+	assert.Error(t, err)
+
+	// The directory is missing, so the file can't be written
+	store, err := OpenFileStore(filepath.Join(dir, "missing", "jobs.json"))
+	require.NoError(t, err)
+	assert.Error(t, store.Save(JobRecord{ID: "a", Schedule: mustSchedule(t, "* * * * *")}))
+
+	// Changes which weren't written aren't kept in memory either
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestSchedulerStore(t *testing.T) {
+	store := NewMemoryStore()
+	s, clock := startScheduler(t, WithStore(store))
+	runs := make(chan time.Time, 10)
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, runs), WithPriority(2)))
+
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 2, records[0].Priority)
+	assert.True(t, records[0].LastRun.IsZero())
+	assert.Equal(t, epoch.Add(time.Hour), records[0].NextRun)
+
+	// Running the job records when it ran
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	<-runs
+	clock.BlockUntil(1)
+	records, err = store.Jobs()
+	require.NoError(t, err)
+	assert.Equal(t, epoch.Add(time.Hour), records[0].LastRun)
+	assert.Equal(t, epoch.Add(time.Hour*2), records[0].NextRun)
+
+	s.Remove("job")
+	records, err = store.Jobs()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	// Jobs which can't be stored aren't added
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	withCalendar := NewScheduleUTC(ex, WithCalendar(NewDateSet(epoch)))
+	assert.Equal(t, errCalendarSchedule, s.AddJob("calendar", withCalendar, recordRuns(clock, runs)))
+	_, ok := s.Stats("calendar")
+	assert.False(t, ok)
+}
+
+func TestSchedulerStoreAddAgain(t *testing.T) {
+	store := NewMemoryStore()
+	first, clock := startScheduler(t, WithStore(store))
+	runs := make(chan time.Time, 10)
+	require.NoError(t, first.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, runs)))
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour))
+	<-runs
+	clock.BlockUntil(1)
+	require.NoError(t, first.Stop(context.Background()))
+
+	// Adding the job again after a restart keeps when it last ran
+	second, clock := startScheduler(t, WithStore(store))
+	require.NoError(t, second.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, runs)))
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, epoch.Add(time.Hour), records[0].LastRun)
+	assert.Equal(t, epoch.Add(time.Hour*2), records[0].NextRun)
+}
+
+// blockingStore is a MemoryStore whose Save waits to be released.
+type blockingStore struct {
+	*MemoryStore
+	saving, release chan struct{}
+}
+
+func (b *blockingStore) Save(record JobRecord) error {
+	b.saving <- struct{}{}
+	<-b.release
+	return b.MemoryStore.Save(record)
+}
+
+func TestSchedulerStoreUnlocked(t *testing.T) {
+	store := &blockingStore{MemoryStore: NewMemoryStore(), saving: make(chan struct{}), release: make(chan struct{})}
+	s, clock := startScheduler(t, WithStore(store))
+	added := make(chan error)
+	go func() {
+		added <- s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, nil))
+	}()
+
+	// The scheduler can be used while the job is being saved, but its ID is taken
+	<-store.saving
+	_, ok := s.Stats("other")
+	assert.False(t, ok)
+	assert.Equal(t, ErrJobExists, s.AddJob("job", mustScheduleClock(t, "0 * * * *", clock), recordRuns(clock, nil)))
+
+	close(store.release)
+	require.NoError(t, <-added)
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, epoch.Add(time.Hour), records[0].NextRun)
+}
+
+func TestRestore(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   MisfirePolicy
+		expected []time.Time
+	}{
+		{"fire once", MisfireFireOnce, []time.Time{epoch.Add(time.Hour * 3)}},
+		{"skip", MisfireSkip, nil},
+		{"fire all", MisfireFireAll, []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2), epoch.Add(time.Hour * 3)}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			// The job last ran at midnight and the scheduler was restarted at 03:30.
+			store := NewMemoryStore()
+			require.NoError(t, store.Save(JobRecord{ID: "job", Schedule: mustSchedule(t, "0 * * * *"), Misfire: test.policy}))
+			require.NoError(t, store.SetRun("job", epoch, epoch.Add(time.Hour)))
+			require.NoError(t, store.Save(JobRecord{ID: "unknown", Schedule: mustSchedule(t, "0 * * * *")}))
+
+			clock := NewFakeClock(epoch.Add(time.Hour*3 + time.Minute*30))
+			s := NewScheduler(WithSchedulerClock(clock), WithStore(store))
+			runs := make(chan time.Time, 10)
+			require.NoError(t, s.Restore(func(id string) JobFunc {
+				if id != "job" {
+					return nil
+				}
+				return func(ctx context.Context) error {
+					runs <- ScheduledTime(ctx)
+					return nil
+				}
+			}))
+			go s.Start()
+			defer s.Stop(context.Background())
+
+			var fired []time.Time
+			for range test.expected {
+				fired = append(fired, <-runs)
+			}
+			assert.ElementsMatch(t, test.expected, fired)
+
+			// Jobs without a function aren't added, but are left in the store
+			_, ok := s.Stats("unknown")
+			assert.False(t, ok)
+			records, err := store.Jobs()
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			assert.Equal(t, epoch.Add(time.Hour*3), records[0].LastRun)
+			assert.Equal(t, epoch.Add(time.Hour*4), records[0].NextRun)
+
+			// Then carries on as normal
+			clock.BlockUntil(1)
+			clock.Set(epoch.Add(time.Hour * 4))
+			assert.Equal(t, epoch.Add(time.Hour*4), <-runs)
+		})
+	}
+}
+
+func TestRestoreNeverRan(t *testing.T) {
+	// The job was added at midnight and first due at 01:00, but the scheduler stopped before then
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(JobRecord{ID: "job", Schedule: mustSchedule(t, "0 * * * *"), Misfire: MisfireFireAll,
+		NextRun: epoch.Add(time.Hour)}))
+
+	clock := NewFakeClock(epoch.Add(time.Hour*2 + time.Minute*30))
+	s := NewScheduler(WithSchedulerClock(clock), WithStore(store))
+	runs := make(chan time.Time, 10)
+	require.NoError(t, s.Restore(func(string) JobFunc {
+		return func(ctx context.Context) error {
+			runs <- ScheduledTime(ctx)
+			return nil
+		}
+	}))
+	go s.Start()
+	defer s.Stop(context.Background())
+
+	assert.ElementsMatch(t, []time.Time{epoch.Add(time.Hour), epoch.Add(time.Hour * 2)}, []time.Time{<-runs, <-runs})
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	assert.Equal(t, epoch.Add(time.Hour*2), records[0].LastRun)
+	assert.Equal(t, epoch.Add(time.Hour*3), records[0].NextRun)
+}
+
+func TestRestoreLongOutage(t *testing.T) {
+	// The job runs every minute and the scheduler was stopped for a year
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(JobRecord{ID: "job", Schedule: mustSchedule(t, "* * * * *"), Misfire: MisfireSkip}))
+	require.NoError(t, store.SetRun("job", epoch, epoch.Add(time.Minute)))
+
+	now := epoch.AddDate(1, 0, 0).Add(time.Second * 30)
+	listener := &countingListener{}
+	s := NewScheduler(WithSchedulerClock(NewFakeClock(now)), WithStore(store), WithListener(listener))
+	require.NoError(t, s.Restore(func(string) JobFunc {
+		return func(context.Context) error { return nil }
+	}))
+
+	// Only the latest missed times are handled
+	assert.Equal(t, maxMissed, listener.misfired)
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	assert.Equal(t, now.Truncate(time.Minute), records[0].LastRun)
+	assert.Equal(t, now.Truncate(time.Minute).Add(time.Minute), records[0].NextRun)
+}
+
+// countingListener counts the misfires it is told about. It is called synchronously, so needs no locking.
+type countingListener struct {
+	NopListener
+	misfired int
+}
+
+func (l *countingListener) OnMisfire(Event) {
+	l.misfired++
+}
+
+func TestRestoreErrors(t *testing.T) {
+	lookup := func(string) JobFunc {
+		return func(context.Context) error { return nil }
+	}
+	assert.Error(t, NewScheduler().Restore(lookup))
+
+	// A job which can't be restored doesn't stop the others
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(JobRecord{ID: "a"}))
+	require.NoError(t, store.Save(JobRecord{ID: "b", Schedule: mustSchedule(t, "0 * * * *")}))
+	s := NewScheduler(WithStore(store))
+	assert.Error(t, s.Restore(lookup))
+	_, ok := s.Stats("b")
+	assert.True(t, ok)
+}
+
+func TestRestoreAdded(t *testing.T) {
+	// The jobs last ran at midnight, and one of them is added before restoring at 02:30
+	store := NewMemoryStore()
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, store.Save(JobRecord{ID: id, Schedule: mustSchedule(t, "0 * * * *"), Misfire: MisfireSkip}))
+		require.NoError(t, store.SetRun(id, epoch, epoch.Add(time.Hour)))
+	}
+
+	clock := NewFakeClock(epoch.Add(time.Hour*2 + time.Minute*30))
+	s := NewScheduler(WithSchedulerClock(clock), WithStore(store))
+	run := func(context.Context) error { return nil }
+	require.NoError(t, s.AddJob("a", mustScheduleClock(t, "0 * * * *", clock), run))
+	require.NoError(t, s.Restore(func(string) JobFunc {
+		return run
+	}))
+
+	// The added job is left alone, and the other is still restored with its missed runs saved
+	_, ok := s.Stats("b")
+	assert.True(t, ok)
+	records, err := store.Jobs()
+	require.NoError(t, err)
+	assert.Equal(t, epoch, records[0].LastRun)
+	assert.Equal(t, epoch.Add(time.Hour*2), records[1].LastRun)
+	assert.Equal(t, epoch.Add(time.Hour*3), records[1].NextRun)
+}
@@ -0,0 +1,293 @@
+package tokei
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ScheduleTimer is a timer which runs on the cron schedule.
+type ScheduleTimer struct {
+	// Counters are accessed atomically, so are kept first for alignment.
+	dropped, coalesced uint64
+
+	schedule  *Schedule
+	timeChan  chan time.Time
+	tickChan  chan Tick
+	closeChan chan struct{}
+	closeOnce sync.Once
+
+	clock  Clock
+	jitter Jitter
+
+	checkInterval    time.Duration
+	misfirePolicy    MisfirePolicy
+	misfireThreshold time.Duration
+	misfireHandler   func(missed []time.Time)
+	fireHandler      func(tick Tick)
+
+	// lastFired is the latest scheduled time the timer has handled, whether or not it was delivered.
+	lastFired time.Time
+
+	delivery DeliveryPolicy
+	buffer   int
+	pending  []Tick
+
+	listeners []Listener
+	name      string
+	metrics   Metrics
+}
+
+// Defaults for how the timer watches the clock.
+const (
+	// DefaultCheckInterval is the longest a timer sleeps before checking the clock again.
+	DefaultCheckInterval = time.Minute
+	// DefaultMisfireThreshold is how late a timer can notice a time before it counts as missed.
+	DefaultMisfireThreshold = time.Minute
+)
+
+// Tick describes a single firing of a ScheduleTimer.
+type Tick struct {
+	// Scheduled is the time which matched the schedule.
+	Scheduled time.Time
+	// Fired is when the timer actually fired, which is after Scheduled if the timer has jitter.
+	Fired time.Time
+}
+
+// TimerOption configures optional behaviour of a ScheduleTimer.
+type TimerOption func(*ScheduleTimer)
+
+// WithJitter delays each firing of the timer by the amount chosen by j.
+func WithJitter(j Jitter) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.jitter = j
+	}
+}
+
+// WithTimerClock sets the clock the timer waits on. It defaults to the schedule's clock.
+func WithTimerClock(clock Clock) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.clock = clock
+	}
+}
+
+// WithCheckInterval sets the longest the timer sleeps before checking the clock again, which bounds how long
+// it takes to notice that the clock has jumped or the machine was suspended. A zero interval sleeps until the next time.
+func WithCheckInterval(d time.Duration) TimerOption {
+	return func(st *ScheduleTimer) {
+		st.checkInterval = d
+	}
+}
+
+// NewScheduleTimer creates a new timer.
+func NewScheduleTimer(schedule *Schedule, opts ...TimerOption) *ScheduleTimer {
+	st := &ScheduleTimer{
+		schedule:         schedule,
+		timeChan:         make(chan time.Time),
+		tickChan:         make(chan Tick),
+		closeChan:        make(chan struct{}),
+		clock:            schedule.clock,
+		checkInterval:    DefaultCheckInterval,
+		misfireThreshold: DefaultMisfireThreshold,
+	}
+	for _, opt := range opts {
+		opt(st)
+	}
+	return st
+}
+
+// Next returns a channel which upon which times will be sent when
+// the schedule matches the cron expression. The time sent is the scheduled time, even if the timer has jitter.
+// Timers must be started with Start().
+func (st *ScheduleTimer) Next() <-chan time.Time {
+	return st.timeChan
+}
+
+// Ticks returns a channel which receives the scheduled and actual time whenever the timer fires.
+// Each firing is sent on either Ticks() or Next(), so a timer should only be read from one of them.
+func (st *ScheduleTimer) Ticks() <-chan Tick {
+	return st.tickChan
+}
+
+// Start starts the timer. It returns once the timer is stopped, or once the schedule is exhausted
+// and every waiting tick has been received, closing the channels returned by Next() and Ticks().
+func (st *ScheduleTimer) Start() {
+	defer close(st.tickChan)
+	defer close(st.timeChan)
+	if st.metrics != nil {
+		st.metrics.AddActiveTimers(1)
+		defer st.metrics.AddActiveTimers(-1)
+	}
+	if !st.catchUp() {
+		return
+	}
+	for {
+		now := st.clock.Now()
+		next := st.next(now)
+		if st.metrics != nil {
+			st.metrics.SetNextFire(st.name, next)
+		}
+		if next.IsZero() {
+			st.flush()
+			return
+		}
+		fire := next
+		if st.jitter != nil {
+			fire = fire.Add(st.jitter.Delay(next, st.interval(next)))
+		}
+		reached, ok := st.waitUntil(fire, now)
+		if !ok {
+			return
+		}
+		if !reached {
+			// The clock went backwards, so an earlier time may be next.
+			continue
+		}
+		now = st.clock.Now()
+		if !st.fire(st.due(next, now), fire, now) {
+			return
+		}
+	}
+}
+
+// Stop stops the timer. It is safe to call more than once.
+func (st *ScheduleTimer) Stop() {
+	st.closeOnce.Do(func() {
+		close(st.closeChan)
+	})
+}
+
+// next returns the next scheduled time from now which the timer hasn't already handled, so that each time
+// fires at most once even if the clock hasn't moved on from it or has gone backwards.
+func (st *ScheduleTimer) next(now time.Time) time.Time {
+	if !st.lastFired.IsZero() && !now.After(st.lastFired) {
+		now = after(st.lastFired)
+	}
+	return st.schedule.NextFrom(now)
+}
+
+// waitUntil waits for the clock to reach t, reporting whether it did. It stops waiting early if the clock goes back
+// before seen, the last time read from it, and returns false for ok if the timer is stopped first.
+// It sleeps for at most the check interval at a time and then reads the clock again. Sleeping is measured
+// on a monotonic clock which can stop while the machine is suspended, so a single long sleep could wake
+// hours after t, and wouldn't notice the wall clock being changed.
+func (st *ScheduleTimer) waitUntil(t, seen time.Time) (reached, ok bool) {
+	for {
+		now := st.clock.Now()
+		if now.Before(seen) {
+			return false, true
+		}
+		seen = now
+		remaining := t.Sub(now)
+		if remaining <= 0 {
+			return true, true
+		}
+		if st.checkInterval > 0 && remaining > st.checkInterval {
+			remaining = st.checkInterval
+		}
+		if !st.sleep(remaining) {
+			return false, false
+		}
+	}
+}
+
+// sleep waits for d to pass, returning false if the timer is stopped first.
+// Waiting ticks are sent to the reader while it sleeps.
+func (st *ScheduleTimer) sleep(d time.Duration) bool {
+	timer := st.clock.NewTimer(d)
+	for {
+		timeChan, tickChan, tick := st.outbox()
+		select {
+		case <-timer.C():
+			return true
+		case <-st.closeChan:
+			timer.Stop()
+			return false
+		case timeChan <- tick.Scheduled:
+			st.sent()
+		case tickChan <- tick:
+			st.sent()
+		}
+	}
+}
+
+// interval returns the time between next and the following time on the schedule.
+func (st *ScheduleTimer) interval(next time.Time) time.Duration {
+	following := st.schedule.NextFrom(after(next))
+	if following.IsZero() {
+		return 0
+	}
+	return following.Sub(next)
+}
+
+// Jitter chooses how long to delay a timer after its scheduled time, so that many
+// timers on the same schedule don't all fire at once.
+type Jitter interface {
+	// Delay returns how long after scheduled the timer should fire.
+	// interval is the time until the next scheduled time, or 0 if there isn't one.
+	Delay(scheduled time.Time, interval time.Duration) time.Duration
+}
+
+// JitterFunc adapts a func to Jitter.
+type JitterFunc func(scheduled time.Time, interval time.Duration) time.Duration
+
+// Delay calls f.
+func (f JitterFunc) Delay(scheduled time.Time, interval time.Duration) time.Duration {
+	return f(scheduled, interval)
+}
+
+// MaxJitter delays timers by a random duration in [0, max).
+// Random numbers come from source, which can be seeded to make delays repeatable. A nil source is seeded from the current time.
+func MaxJitter(max time.Duration, source rand.Source) Jitter {
+	rng := newLockedRand(source)
+	return JitterFunc(func(time.Time, time.Duration) time.Duration {
+		return rng.duration(max)
+	})
+}
+
+// PercentJitter delays timers by a random duration up to percent of the interval until the next scheduled time.
+// Random numbers come from source, as with MaxJitter.
+func PercentJitter(percent float64, source rand.Source) Jitter {
+	rng := newLockedRand(source)
+	return JitterFunc(func(_ time.Time, interval time.Duration) time.Duration {
+		return rng.duration(time.Duration(float64(interval) * percent / 100))
+	})
+}
+
+// KeyJitter delays timers by a fixed duration in [0, max) derived from key.
+// Timers with different keys, such as the name of each pod, are spread out while each one always fires at the same offset.
+func KeyJitter(key string, max time.Duration) Jitter {
+	var offset time.Duration
+	if max > 0 {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		offset = time.Duration(hash.Sum64() % uint64(max))
+	}
+	return JitterFunc(func(time.Time, time.Duration) time.Duration {
+		return offset
+	})
+}
+
+// lockedRand is a random number generator which is safe to share between timers.
+type lockedRand struct {
+	sync.Mutex
+	rng *rand.Rand
+}
+
+func newLockedRand(source rand.Source) *lockedRand {
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &lockedRand{rng: rand.New(source)}
+}
+
+// duration returns a random duration in [0, max), or 0 if max isn't positive.
+func (r *lockedRand) duration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	r.Lock()
+	defer r.Unlock()
+	return time.Duration(r.rng.Int63n(int64(max)))
+}
@@ -0,0 +1,250 @@
+package tokei
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimerMaxOccurrences(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	timer := NewScheduleUTC(ex, MaxOccurrences(10, epoch)).Timer()
+	timer.Start()
+
+	_, ok := <-timer.Next()
+	assert.False(t, ok)
+}
+
+func TestTimerExhausted(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	timer := NewScheduleUTC(ex, NotAfter(epoch)).Timer()
+	timer.Start()
+
+	_, ok := <-timer.Next()
+	assert.False(t, ok)
+}
+
+func TestTimer(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer()
+	go timer.Start()
+	defer timer.Stop()
+
+	// Should receive at the start of the next minute
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+
+	// And again at the start of the following one
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute*2), <-timer.Next())
+}
+
+func TestTimerMonths(t *testing.T) {
+	ex, err := Parse("0 9 1 * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Hour))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer()
+	go timer.Start()
+	defer timer.Stop()
+
+	for month := time.January; month <= time.December; month++ {
+		clock.BlockUntil(1)
+		clock.Set(time.Date(1970, month, 1, 9, 0, 30, 0, time.UTC))
+		assert.Equal(t, time.Date(1970, month, 1, 9, 0, 0, 0, time.UTC), <-timer.Next())
+	}
+}
+
+func TestTimerStop(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex).Timer(WithTimerClock(clock))
+
+	stopped := make(chan struct{})
+	go func() {
+		timer.Start()
+		close(stopped)
+	}()
+	clock.BlockUntil(1)
+	timer.Stop()
+	timer.Stop()
+
+	<-stopped
+	_, ok := <-timer.Next()
+	assert.False(t, ok)
+}
+
+func TestTimerStopWhileDelivering(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer()
+
+	stopped := make(chan struct{})
+	go func() {
+		timer.Start()
+		close(stopped)
+	}()
+
+	// Nothing reads the tick, so the timer is stuck delivering it until stopped.
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	timer.Stop()
+	<-stopped
+}
+
+func TestTimerLong(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+		return
+	}
+	// Every other minute, starting with whichever is next, so that the first time is within a minute
+	startTime := time.Now()
+	ex, err := Parse(fmt.Sprintf("%d/2 * * * *", (startTime.Minute()+1)%2))
+	require.NoError(t, err)
+	schedule := NewScheduleUTC(ex)
+
+	timer := schedule.Timer()
+	go timer.Start()
+
+	out := <-timer.Next()
+	// Should fire within the next minute (+ a second buffer.)
+	assert.WithinDuration(t, startTime, out, time.Minute+time.Second)
+}
+
+func TestTimerJitter(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Second * 10))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithJitter(KeyJitter("pod-1", time.Second*30)))
+	go timer.Start()
+	defer timer.Stop()
+
+	delay := KeyJitter("pod-1", time.Second*30).Delay(epoch, time.Minute)
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute + delay))
+
+	tick := <-timer.Ticks()
+	assert.Equal(t, epoch.Add(time.Minute), tick.Scheduled)
+	assert.Equal(t, epoch.Add(time.Minute+delay), tick.Fired)
+}
+
+func TestMaxJitter(t *testing.T) {
+	first := MaxJitter(time.Minute, rand.NewSource(1))
+	second := MaxJitter(time.Minute, rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		delay := first.Delay(epoch, time.Hour)
+		assert.True(t, delay >= 0 && delay < time.Minute)
+		assert.Equal(t, delay, second.Delay(epoch, time.Hour), "seeded jitter should be repeatable")
+	}
+
+	assert.Equal(t, time.Duration(0), MaxJitter(0, nil).Delay(epoch, time.Hour))
+}
+
+func TestPercentJitter(t *testing.T) {
+	jitter := PercentJitter(10, rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		delay := jitter.Delay(epoch, time.Hour)
+		assert.True(t, delay >= 0 && delay < time.Minute*6)
+	}
+
+	assert.Equal(t, time.Duration(0), jitter.Delay(epoch, 0))
+}
+
+func TestKeyJitter(t *testing.T) {
+	first := KeyJitter("pod-1", time.Minute)
+	delay := first.Delay(epoch, time.Hour)
+	assert.True(t, delay >= 0 && delay < time.Minute)
+	assert.Equal(t, delay, first.Delay(epoch.Add(time.Hour), time.Minute))
+	assert.Equal(t, delay, KeyJitter("pod-1", time.Minute).Delay(epoch, time.Hour))
+	assert.NotEqual(t, delay, KeyJitter("pod-2", time.Minute).Delay(epoch, time.Hour))
+
+	assert.Equal(t, time.Duration(0), KeyJitter("pod-1", 0).Delay(epoch, time.Hour))
+}
+
+func TestTimerInterval(t *testing.T) {
+	ex, err := Parse("0 9,17 * * *")
+	require.NoError(t, err)
+	timer := NewScheduleUTC(ex, NotAfter(epoch.Add(time.Hour*17))).Timer()
+
+	assert.Equal(t, time.Hour*8, timer.interval(epoch.Add(time.Hour*9)))
+	assert.Equal(t, time.Duration(0), timer.interval(epoch.Add(time.Hour*17)))
+}
+
+func TestTimerFiresOnce(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+
+	// Starting exactly on a matching minute fires for it straight away
+	clock := NewFakeClock(epoch)
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithCheckInterval(time.Second * 10))
+	go timer.Start()
+	defer timer.Stop()
+	assert.Equal(t, epoch, <-timer.Next())
+
+	// But not again while the clock is still within that minute
+	clock.BlockUntil(1)
+	clock.Advance(time.Second * 59)
+	clock.BlockUntil(1)
+	assertNotFired(t, timer.Next())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+}
+
+func TestTimerClockBackwards(t *testing.T) {
+	ex, err := Parse("0 * * * *")
+	require.NoError(t, err)
+	clock := NewFakeClock(epoch.Add(time.Hour*9 + time.Minute*30))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithCheckInterval(time.Minute * 10))
+	go timer.Start()
+	defer timer.Stop()
+
+	// Waiting for 10:00 notices the clock going back to 08:10 and waits for 09:00 instead
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour*8 + time.Minute*10))
+	clock.Advance(time.Minute * 10)
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Hour * 9))
+	assert.Equal(t, epoch.Add(time.Hour*9), <-timer.Next())
+}
+
+func TestTimerNextAfterFired(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+	schedule := NewScheduleUTC(ex)
+
+	assert.Equal(t, epoch, schedule.Timer().next(epoch))
+
+	// Times which have already fired don't fire again, even if the clock goes backwards
+	timer := schedule.Timer(WithLastFired(epoch.Add(time.Minute)))
+	assert.Equal(t, epoch.Add(time.Minute*2), timer.next(epoch))
+	assert.Equal(t, epoch.Add(time.Minute*2), timer.next(epoch.Add(time.Minute)))
+	assert.Equal(t, epoch.Add(time.Minute*2), timer.next(epoch.Add(time.Minute+time.Second*30)))
+	assert.Equal(t, epoch.Add(time.Minute*3), timer.next(epoch.Add(time.Minute*2+time.Second)))
+}
+
+func TestTimerLastFired(t *testing.T) {
+	ex, err := Parse("* * * * *")
+	require.NoError(t, err)
+
+	// Restarting within the minute which last fired doesn't fire it again
+	clock := NewFakeClock(epoch.Add(time.Second * 30))
+	timer := NewScheduleUTC(ex, WithClock(clock)).Timer(WithLastFired(epoch))
+	go timer.Start()
+	defer timer.Stop()
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute))
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.Next())
+}
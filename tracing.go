@@ -0,0 +1,79 @@
+package tokei
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TracedRun describes an attempt at running a job to a Tracer.
+type TracedRun struct {
+	// JobID is the ID of the job.
+	JobID string
+	// Expression is the cron expression of the job's schedule.
+	Expression string
+	// Scheduled is the time the run was scheduled for.
+	Scheduled time.Time
+	// Started is when the attempt started.
+	Started time.Time
+	// Lag is how long after its scheduled time the attempt started, including waiting for a worker and earlier attempts.
+	Lag time.Duration
+	// Attempt is which attempt at running the job this is, starting from 1.
+	Attempt int
+}
+
+// Tracer traces the runs of a Scheduler's jobs, such as by starting a span for each one, so that the calls a job
+// makes can be linked to the run which made them.
+type Tracer interface {
+	// Start is called before each attempt at running a job. It returns the context to run the job with, which
+	// usually carries a new span, and a function which is called with the job's error once it returns,
+	// which usually ends the span. If the job panics, the function is called with an error describing the panic.
+	Start(ctx context.Context, run TracedRun) (context.Context, func(err error))
+}
+
+// NopTracer is a Tracer which does nothing. It is the default.
+type NopTracer struct{}
+
+// Start returns ctx unchanged.
+func (NopTracer) Start(ctx context.Context, _ TracedRun) (context.Context, func(err error)) {
+	return ctx, func(error) {}
+}
+
+// WithTracer traces the runs of the scheduler's jobs with t.
+func WithTracer(t Tracer) SchedulerOption {
+	return func(s *Scheduler) {
+		s.tracer = t
+	}
+}
+
+// trace starts tracing an attempt at a run, returning the context to run the job with and the function to end it.
+func (s *Scheduler) trace(ctx context.Context, r *run, attempt int, started time.Time) (context.Context, func(err error)) {
+	return s.tracer.Start(ctx, TracedRun{
+		JobID:      r.job.id,
+		Expression: r.job.schedule.Expression().String(),
+		Scheduled:  r.scheduled,
+		Started:    started,
+		Lag:        started.Sub(r.scheduled),
+		Attempt:    attempt,
+	})
+}
+
+// runTraced runs a job and ends its span with the job's error. If the job panics, the span is ended with an error
+// describing the panic before the panic carries on.
+func runTraced(ctx context.Context, run JobFunc, endSpan func(err error)) error {
+	returned := false
+	defer func() {
+		if returned {
+			return
+		}
+		p := recover()
+		endSpan(fmt.Errorf("job panicked: %v", p))
+		if p != nil {
+			panic(p)
+		}
+	}()
+	err := run(ctx)
+	returned = true
+	endSpan(err)
+	return err
+}
@@ -0,0 +1,72 @@
+package tokei
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spanKey is the context key for the span a recordingTracer starts.
+type spanKey struct{}
+
+// recordingTracer sends every run it traces on runs, and the error each one ended with on ended.
+type recordingTracer struct {
+	runs  chan TracedRun
+	ended chan error
+}
+
+func (tr *recordingTracer) Start(ctx context.Context, run TracedRun) (context.Context, func(err error)) {
+	tr.runs <- run
+	return context.WithValue(ctx, spanKey{}, run.JobID), func(err error) {
+		tr.ended <- err
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{runs: make(chan TracedRun, 10), ended: make(chan error, 10)}
+	s, clock := startScheduler(t, WithTracer(tracer))
+	failed := errors.New("failed")
+	spans := make(chan interface{}, 10)
+	require.NoError(t, s.AddJob("job", mustScheduleClock(t, "*/5 * * * *", clock), func(ctx context.Context) error {
+		// The job is run with the context from the tracer
+		spans <- ctx.Value(spanKey{})
+		assert.Equal(t, "job", JobID(ctx))
+		return failed
+	}))
+
+	clock.BlockUntil(1)
+	clock.Set(epoch.Add(time.Minute*5 + time.Second*3))
+	assert.Equal(t, TracedRun{
+		JobID:      "job",
+		Expression: "*/5 * * * *",
+		Scheduled:  epoch.Add(time.Minute * 5),
+		Started:    epoch.Add(time.Minute*5 + time.Second*3),
+		Lag:        time.Second * 3,
+		Attempt:    1,
+	}, <-tracer.runs)
+	assert.Equal(t, "job", <-spans)
+	assert.Equal(t, failed, <-tracer.ended)
+}
+
+func TestTracerPanic(t *testing.T) {
+	ended := make(chan error, 1)
+	assert.PanicsWithValue(t, "failed", func() {
+		runTraced(context.Background(), func(context.Context) error {
+			panic("failed")
+		}, func(err error) {
+			ended <- err
+		})
+	})
+	assert.EqualError(t, <-ended, "job panicked: failed")
+}
+
+func TestNopTracer(t *testing.T) {
+	ctx := context.Background()
+	traced, end := NopTracer{}.Start(ctx, TracedRun{JobID: "job"})
+	assert.Equal(t, ctx, traced)
+	end(nil)
+}